@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/NachoGz/chirpy/internal/memstore"
+	"github.com/NachoGz/chirpy/internal/metrics"
+	"github.com/NachoGz/chirpy/internal/moderation"
+)
+
+const (
+	testPolkaSecret = "test-polka-secret"
+	testAdminKey    = "test-admin-key"
+	testPassword    = "hunter2"
+)
+
+// newTestServer runs the full API against an in-memory store, or against
+// the migrated database in DBURL if a configure function sets one.
+// configure functions may adjust the configuration first; by default the
+// platform is dev.
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *httptest.Server {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Platform = "dev"
+	cfg.JWT.Secret = "test-secret"
+	cfg.Polka.Secrets = []string{testPolkaSecret}
+	cfg.AdminAPIKey = testAdminKey
+	for _, f := range configure {
+		f(&cfg)
+	}
+
+	apiCfg := &apiConfig{
+		jwtKeys: auth.NewKeySet(cfg.JWT.Secret),
+		config:  cfg,
+		metrics: metrics.New(nil),
+		mailer:  newMailer(cfg),
+	}
+	if cfg.DBURL == "" {
+		apiCfg.db = memstore.New()
+	} else {
+		db, backend, err := openDatabase(cfg.DBURL)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := migrateUp(context.Background(), db, backend); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+		apiCfg.db, apiCfg.dbConn, apiCfg.backend = backend.queries(db), db, backend
+	}
+
+	moderator := moderation.New(moderation.WordList(moderation.DefaultWords), dbRuleSource{db: apiCfg.db})
+	if err := moderator.Reload(context.Background()); err != nil {
+		t.Fatalf("failed to load moderation rules: %v", err)
+	}
+
+	apiCfg.moderator = moderator
+	server := httptest.NewServer(apiCfg.routes())
+	t.Cleanup(server.Close)
+
+	// The background jobs run alongside, as they do in main
+	ctx, cancel := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	jobs.Add(2)
+	go func() {
+		defer jobs.Done()
+		apiCfg.expireSubscriptions(ctx, cfg.Subscriptions.CheckInterval)
+	}()
+	go func() {
+		defer jobs.Done()
+		apiCfg.publishScheduledChirps(ctx, cfg.PublishInterval)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		jobs.Wait()
+		apiCfg.background.Wait()
+	})
+	return server
+}
+
+// doJSON sends body as JSON and decodes the response into out, if given.
+// token is sent as a bearer token unless it already names a scheme.
+func doJSON(t *testing.T, method, url, token string, body, out any) *http.Response {
+	t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &reqBody)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if strings.Contains(token, " ") {
+		// Already a full credential, such as "ApiKey ..."
+		req.Header.Set("Authorization", token)
+	} else if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer res.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, url, err)
+		}
+	}
+	return res
+}
+
+func TestChirpWithMemoryStore(t *testing.T) {
+	server := newTestServer(t)
+	credentials := map[string]string{"email": "walt@breakingbad.com", "password": "04234"}
+
+	if res := doJSON(t, "POST", server.URL+"/api/users", "", credentials, nil); res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 creating a user, got %d", res.StatusCode)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/login", "", credentials, &login); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 logging in, got %d", res.StatusCode)
+	}
+
+	var chirp Chirp
+	res := doJSON(t, "POST", server.URL+"/api/chirps", login.Token, map[string]string{"body": "What a kerfuffle"}, &chirp)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 posting a chirp, got %d", res.StatusCode)
+	}
+	if chirp.Body != "What a ****" {
+		t.Errorf("expected the body to be moderated, got %q", chirp.Body)
+	}
+
+	var page ChirpPage
+	doJSON(t, "GET", server.URL+"/api/chirps", "", nil, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0].ID != chirp.ID {
+		t.Errorf("expected the new chirp to be listed, got %+v", page.Chirps)
+	}
+}
+
+type testUser struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// signUp registers a user and logs them in.
+func signUp(t *testing.T, server *httptest.Server, email string) testUser {
+	t.Helper()
+
+	credentials := map[string]string{"email": email, "password": testPassword}
+	if res := doJSON(t, "POST", server.URL+"/api/users", "", credentials, nil); res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 creating %s, got %d", email, res.StatusCode)
+	}
+	return signIn(t, server, email)
+}
+
+// signIn logs in a user created by signUp.
+func signIn(t *testing.T, server *httptest.Server, email string) testUser {
+	t.Helper()
+
+	credentials := map[string]string{"email": email, "password": testPassword}
+	var user testUser
+	if res := doJSON(t, "POST", server.URL+"/api/login", "", credentials, &user); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 logging in as %s, got %d", email, res.StatusCode)
+	}
+	return user
+}
+
+// postChirp posts body as user and returns the new chirp.
+func postChirp(t *testing.T, server *httptest.Server, user testUser, body string) Chirp {
+	t.Helper()
+
+	var chirp Chirp
+	res := doJSON(t, "POST", server.URL+"/api/chirps", user.Token, map[string]string{"body": body}, &chirp)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 posting a chirp, got %d", res.StatusCode)
+	}
+	return chirp
+}
+
+func TestReadyzWithMemoryStore(t *testing.T) {
+	server := newTestServer(t)
+
+	var response readinessResponse
+	if res := doJSON(t, "GET", server.URL+"/api/readyz", "", nil, &response); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if response.Status != "ok" || len(response.Checks) != 0 {
+		t.Errorf("expected no checks to run without a database, got %+v", response)
+	}
+}
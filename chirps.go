@@ -3,17 +3,18 @@ package main
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 	"github.com/google/uuid"
+	"github.com/NachoGz/chirpy/internal/chirptext"
 	"github.com/NachoGz/chirpy/internal/database"
-	"github.com/NachoGz/chirpy/internal/auth"
-	"log"
-	"sort"
+	"log/slog"
+	"time"
 )
 
 func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body		string		`json:"body"`
+		InReplyToID	*uuid.UUID	`json:"in_reply_to_id"`
+		PublishAt	*time.Time	`json:"publish_at"`
 	}
 
 
@@ -26,189 +27,289 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 	}
 
 
-	// Extract token from the header
-	bearer_token, err := auth.GetBearerToken(r.Header)
+	userID := requestUserID(r)
+	policy, err := cfg.tierPolicy(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Missing or invalid authorization token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
 		return
 	}
 
 
-	// Validate the JWT and extract user ID
-	userID, err := auth.ValidateJWT(bearer_token, cfg.secret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect token", err)
+	// Validate chirp length
+	body := chirptext.Normalize(params.Body)
+	if length, ok := validateChirps(body, policy.MaxChirpLength); !ok {
+		respondChirpTooLong(w, length, policy.MaxChirpLength)
 		return
 	}
 
 
-	// Log the user ID for debugging
-	log.Printf("User ID from JWT: %s", userID.String())
-	
+	// Run the body through the moderation filters
+	moderated := cfg.moderator.Moderate(body)
+	if moderated.Rejected {
+		slog.InfoContext(r.Context(), "Rejected chirp", slog.String("user_id", userID.String()), slog.Any("matches", moderated.Matches))
+		respondWithError(w, http.StatusBadRequest, "Chirp violates the content rules", nil)
+		return
+	}
+	cleaned := moderated.Body
 
-	// Validate chirp length
-	if !validateChirps(params.Body) {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
+
+	// A publish time in the past just means now
+	publishAt := time.Now()
+	scheduled := params.PublishAt != nil && params.PublishAt.After(publishAt)
+	if scheduled {
+		publishAt = *params.PublishAt
+	}
+
+
+	// Enforce the tier's daily quota, for scheduled chirps too, since they
+	// are published without another check. The lock is held until the
+	// chirp is stored so concurrent posts can't both fit in the last slot.
+	unlock := cfg.chirpLocks.lock(userID)
+	defer unlock()
+	if policy.DailyChirps > 0 {
+		used, err := cfg.dailyChirpsUsed(r.Context(), userID, publishAt)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check chirp quota", err)
+			return
+		}
+		if used >= int64(policy.DailyChirps) {
+			respondWithError(w, http.StatusTooManyRequests, "Daily chirp limit reached", nil)
+			return
+		}
+	}
+
+
+	if scheduled {
+		if params.InReplyToID != nil {
+			respondWithError(w, http.StatusBadRequest, "Replies can't be scheduled", nil)
+			return
+		}
+		cfg.scheduleChirp(w, r, policy, cleaned, publishAt)
 		return
 	}
 
 
-	// Clean the chirp body
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	// Replies join the thread of the chirp they answer
+	inReplyToID := uuid.NullUUID{}
+	threadID := uuid.NullUUID{}
+	if params.InReplyToID != nil {
+		parent, err := cfg.db.GetChirpByID(r.Context(), *params.InReplyToID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "The chirp being replied to doesn't exist", err)
+			return
+		}
+		inReplyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		threadID = uuid.NullUUID{UUID: parent.ThreadID, Valid: true}
 	}
-	cleaned := getCleanedBody(params.Body, badWords)
 
 
 	// Create chirp in the database
 	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:	cleaned,
-		UserID:	uuid.NullUUID{UUID:	userID, Valid: true}, // Convert uuid.UUID to uuid.NullUUID
+		Body:			cleaned,
+		UserID:			uuid.NullUUID{UUID:	userID, Valid: true}, // Convert uuid.UUID to uuid.NullUUID
+		InReplyToID:	inReplyToID,
+		ThreadID:		threadID,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
 		return
 	}
+	cfg.metrics.ChirpsCreated.Inc()
 
-	new_chirp := Chirp {
-		ID: 		chirp.ID,
-		CreatedAt: 	chirp.CreatedAt,
-		UpdatedAt: 	chirp.UpdatedAt,
-		Body: 		chirp.Body,
-		UserID:		chirp.UserID.UUID,
-	}
+	new_chirp := chirpFromDB(chirp)
+	liked := false
+	new_chirp.LikedByMe = &liked
 
 	respondWithJSON(w, http.StatusCreated, new_chirp)
 }
 
 
-func getCleanedBody(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		loweredWord := strings.ToLower(word)
-		if _, ok := badWords[loweredWord]; ok {
-			words[i] = "****"
-		}
-	}
-	cleaned := strings.Join(words, " ")
-	return cleaned
+// validateChirps reports the length chirp counts as and whether that fits
+// within maxChirpLength. chirp should already be normalized.
+func validateChirps(chirp string, maxChirpLength int) (int, bool) {
+	length := chirptext.Length(chirp)
+	return length, length <= maxChirpLength
 }
 
-func validateChirps(chirp string) bool {
-	const maxChirpLength = 140
-	if len(chirp) > maxChirpLength {
-		return false
+
+// respondChirpTooLong rejects a chirp over the limit, telling the client
+// how it was counted so it can show a counter.
+func respondChirpTooLong(w http.ResponseWriter, length, limit int) {
+	type tooLongResponse struct {
+		Error	string	`json:"error"`
+		Length	int		`json:"length"`
+		Limit	int		`json:"limit"`
 	}
-	return true
+	respondWithJSON(w, http.StatusBadRequest, tooLongResponse{
+		Error:	"Chirp is too long",
+		Length:	length,
+		Limit:	limit,
+	})
 }
 
 
-func (cfg *apiConfig) handleGetChirps(w http.ResponseWriter, r *http.Request) {
-    var chirps []database.Chirp
-    var err error
-
-    authorIDStr := r.URL.Query().Get("author_id")
-    if authorIDStr != "" {
-        authorID, err := uuid.Parse(authorIDStr)
-        if err != nil {
-            respondWithError(w, http.StatusBadRequest, "Invalid author_id format", err)
-            return
-        }
-        nullUUID := uuid.NullUUID{
-            UUID:  authorID,
-            Valid: true,
-        }
-        chirps, err = cfg.db.GetAllChirpsByID(r.Context(), nullUUID)
-        if err != nil {
-            respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
-            return
-        }
-    } else {
-        chirps, err = cfg.db.GetAllChirps(r.Context())
-        if err != nil {
-            respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
-            return
-        }
-    }
-
-    retrieved_chirps := []Chirp{}
-    for _, chirp := range chirps {
-        retrieved_chirps = append(retrieved_chirps, Chirp{
-            ID:        chirp.ID,
-            CreatedAt: chirp.CreatedAt,
-            UpdatedAt: chirp.UpdatedAt,
-            Body:      chirp.Body,
-            UserID:    chirp.UserID.UUID,
-        })
-    }
-
-    // Get the sort parameter from query string
-    sortOrder := r.URL.Query().Get("sort")
-    
-    if sortOrder == "desc" {
-        sort.Slice(retrieved_chirps, func(i, j int) bool {
-            return retrieved_chirps[i].CreatedAt.After(retrieved_chirps[j].CreatedAt)
-        })
-    } else {
-        sort.Slice(retrieved_chirps, func(i, j int) bool {
-            return retrieved_chirps[i].CreatedAt.Before(retrieved_chirps[j].CreatedAt)
-        })
-    }
-
-    respondWithJSON(w, http.StatusOK, retrieved_chirps)
+// ChirpPage is one page of a chirp listing. Every paginated listing has
+// the same next_cursor and prev_cursor fields: each is passed back as the
+// cursor parameter to get the neighbouring page, and is null when there is
+// no page in that direction. They are also sent in the Link,
+// X-Next-Cursor and X-Prev-Cursor headers.
+type ChirpPage struct {
+	Chirps		[]Chirp	`json:"chirps"`
+	NextCursor	*string	`json:"next_cursor"`
+	PrevCursor	*string	`json:"prev_cursor"`
 }
 
-func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, r *http.Request) {
-	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+
+func (cfg *apiConfig) handleGetChirps(w http.ResponseWriter, r *http.Request) {
+	viewer := viewerID(r)
+
+	authorID, err := parseAuthorID(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't parse chirpID", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid author_id format", err)
 		return
 	}
 
 
-	chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	// Get the sort parameter from query string
+	sortOrder := r.URL.Query().Get("sort")
+	if sortOrder != "" && sortOrder != "asc" && sortOrder != "desc" {
+		respondWithError(w, http.StatusBadRequest, "sort must be asc or desc", nil)
+		return
+	}
+
+
+	page, err := parsePageRequest(r)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
-	
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID: 		chirp.ID,
-		CreatedAt: 	chirp.CreatedAt,
-		UpdatedAt: 	chirp.UpdatedAt,
-		Body: 		chirp.Body,
-		UserID:		chirp.UserID.UUID,
-	})
+
+	// Walking forwards through a descending list, or backwards through an
+	// ascending one, means reading older chirps first.
+	olderFirst := (sortOrder == "desc") != page.backward()
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	// Fetch one extra row to find out whether there is another page
+	var chirps []database.Chirp
+	if olderFirst {
+		chirps, err = cfg.db.ListChirpsBefore(r.Context(), database.ListChirpsBeforeParams{
+			AuthorID:			authorID,
+			CursorCreatedAt:	cursorCreatedAt,
+			CursorID:			cursorID,
+			PageSize:			page.Limit + 1,
+		})
+	} else {
+		chirps, err = cfg.db.ListChirpsAfter(r.Context(), database.ListChirpsAfterParams{
+			AuthorID:			authorID,
+			CursorCreatedAt:	cursorCreatedAt,
+			CursorID:			cursorID,
+			PageSize:			page.Limit + 1,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	chirps, hasMore := trimPage(page, chirps)
+	retrieved_chirps := chirpsFromDB(chirps)
+	if err := cfg.markLikedByMe(r.Context(), retrieved_chirps, viewer); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
+		return
+	}
+
+	next, prev := pageBounds(page, retrieved_chirps, hasMore, chirpPosition)
+	response := ChirpPage{
+		Chirps:		retrieved_chirps,
+		NextCursor:	cursorString(next),
+		PrevCursor:	cursorString(prev),
+	}
+	setPageLinks(w, r, response.NextCursor, response.PrevCursor)
+
+	respondWithJSON(w, http.StatusOK, response)
 }
 
 
-func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+// parseAuthorID reads the optional author_id filter shared by the chirp
+// listing and search endpoints.
+func parseAuthorID(r *http.Request) (uuid.NullUUID, error) {
+	authorIDStr := r.URL.Query().Get("author_id")
+	if authorIDStr == "" {
+		return uuid.NullUUID{}, nil
+	}
+	authorID, err := uuid.Parse(authorIDStr)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: authorID, Valid: true}, nil
+}
+
+func chirpFromDB(chirp database.Chirp) Chirp {
+	return Chirp{
+		ID:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		UserID:      chirp.UserID.UUID,
+		LikeCount:   chirp.LikeCount,
+		InReplyToID: chirp.InReplyToID,
+		ThreadID:    chirp.ThreadID,
+		Edited:      chirp.EditedAt.Valid,
+	}
+}
+
+func chirpsFromDB(chirps []database.Chirp) []Chirp {
+	converted := []Chirp{}
+	for _, chirp := range chirps {
+		converted = append(converted, chirpFromDB(chirp))
+	}
+	return converted
+}
+
+func chirpPosition(c Chirp) pageCursor {
+	return pageCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, r *http.Request) {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't parse chirpID", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse chirpID", err)
 		return
 	}
 
 
-	// Extract token from the header
-	bearer_token, err := auth.GetBearerToken(r.Header)
+	viewer := viewerID(r)
+
+	chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Missing or invalid authorization token", err)
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		return
+	}
+	
+
+	retrieved_chirp := []Chirp{chirpFromDB(chirp)}
+	if err := cfg.markLikedByMe(r.Context(), retrieved_chirp, viewer); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, retrieved_chirp[0])
+}
+
 
-	// Validate the JWT and extract user ID
-	userID, err := auth.ValidateJWT(bearer_token, cfg.secret)
+func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect token", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse chirpID", err)
 		return
 	}
 
 
+	userID := requestUserID(r)
+
+
 	to_delete_chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/google/uuid"
+)
+
+func TestCreateChirp(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	url := server.URL + "/api/chirps"
+
+	chirp := postChirp(t, server, user, "I am the one who knocks")
+	if chirp.UserID != user.ID || chirp.Body != "I am the one who knocks" || chirp.LikeCount != 0 {
+		t.Errorf("unexpected chirp %+v", chirp)
+	}
+
+	res := doJSON(t, "POST", url, "", map[string]string{"body": "hi"}, nil)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+	if challenge := res.Header.Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Bearer") {
+		t.Errorf("expected a Bearer challenge, got %q", challenge)
+	}
+
+	res = doJSON(t, "POST", url, "not-a-jwt", map[string]string{"body": "hi"}, nil)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a bad token, got %d", res.StatusCode)
+	}
+	if challenge := res.Header.Get("WWW-Authenticate"); !strings.Contains(challenge, `error="invalid_token"`) {
+		t.Errorf("expected an invalid_token challenge, got %q", challenge)
+	}
+
+	res = doJSON(t, "POST", url, user.Token, map[string]string{"body": strings.Repeat("a", 141)}, nil)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a long chirp, got %d", res.StatusCode)
+	}
+}
+
+func TestGetChirp(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	chirp := postChirp(t, server, user, "Say my name")
+
+	var got Chirp
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/"+chirp.ID.String(), "", nil, &got); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if got.ID != chirp.ID || got.Body != chirp.Body {
+		t.Errorf("expected %+v, got %+v", chirp, got)
+	}
+
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/"+uuid.NewString(), "", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown chirp, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/not-a-uuid", "", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed id, got %d", res.StatusCode)
+	}
+}
+
+func TestListChirps(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+
+	first := postChirp(t, server, walt, "first")
+	second := postChirp(t, server, jesse, "second")
+	third := postChirp(t, server, walt, "third")
+
+	ids := func(chirps []Chirp) []uuid.UUID {
+		var ids []uuid.UUID
+		for _, chirp := range chirps {
+			ids = append(ids, chirp.ID)
+		}
+		return ids
+	}
+	cases := map[string][]uuid.UUID{
+		"":                                {first.ID, second.ID, third.ID},
+		"?sort=asc":                       {first.ID, second.ID, third.ID},
+		"?sort=desc":                      {third.ID, second.ID, first.ID},
+		"?author_id=" + walt.ID.String():  {first.ID, third.ID},
+		"?author_id=" + jesse.ID.String(): {second.ID},
+		"?author_id=" + uuid.NewString():  nil,
+		"?author_id=" + walt.ID.String() + "&sort=desc": {third.ID, first.ID},
+	}
+	for query, expected := range cases {
+		var page ChirpPage
+		if res := doJSON(t, "GET", server.URL+"/api/chirps"+query, "", nil, &page); res.StatusCode != http.StatusOK {
+			t.Errorf("%q: expected 200, got %d", query, res.StatusCode)
+			continue
+		}
+		if got := ids(page.Chirps); !slicesEqual(got, expected) {
+			t.Errorf("%q: expected %v, got %v", query, expected, got)
+		}
+	}
+
+	for _, query := range []string{"?sort=sideways", "?author_id=nope"} {
+		if res := doJSON(t, "GET", server.URL+"/api/chirps"+query, "", nil, nil); res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, res.StatusCode)
+		}
+	}
+}
+
+func TestListChirpsPages(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	for _, body := range []string{"one", "two", "three"} {
+		postChirp(t, server, user, body)
+	}
+
+	var page ChirpPage
+	res := doJSON(t, "GET", server.URL+"/api/chirps?limit=2", "", nil, &page)
+	if len(page.Chirps) != 2 || page.Chirps[0].Body != "one" || page.Chirps[1].Body != "two" {
+		t.Fatalf("unexpected first page %+v", page.Chirps)
+	}
+	if page.NextCursor == nil || *page.NextCursor != res.Header.Get("X-Next-Cursor") || page.PrevCursor != nil {
+		t.Fatalf("expected only a next cursor, matching the header, got %+v", page)
+	}
+
+	next := *page.NextCursor
+	page = ChirpPage{}
+	res = doJSON(t, "GET", server.URL+"/api/chirps?limit=2&cursor="+next, "", nil, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0].Body != "three" {
+		t.Fatalf("unexpected second page %+v", page.Chirps)
+	}
+	if page.NextCursor != nil || res.Header.Get("X-Next-Cursor") != "" {
+		t.Error("expected no cursor after the last page")
+	}
+	if page.PrevCursor == nil {
+		t.Error("expected a cursor back to the first page")
+	}
+}
+
+func TestDeleteChirp(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+	chirp := postChirp(t, server, walt, "I am the danger")
+	url := server.URL + "/api/chirps/" + chirp.ID.String()
+
+	if res := doJSON(t, "DELETE", url, "", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "DELETE", url, jesse.Token, nil, nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 deleting someone else's chirp, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "DELETE", url, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "GET", url, "", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 after deleting, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "DELETE", url, walt.Token, nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %d", res.StatusCode)
+	}
+}
+
+func slicesEqual(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestChirpPerksByTier(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Tiers.Free.DailyChirps = 2
+		cfg.Tiers.Red.DailyChirps = 3
+	})
+	walt := signUp(t, server, "walt@breakingbad.com")
+	url := server.URL + "/api/chirps"
+	post := func(user testUser, body string) int {
+		return doJSON(t, "POST", url, user.Token, map[string]string{"body": body}, nil).StatusCode
+	}
+
+	if code := post(walt, strings.Repeat("a", 141)); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a chirp over the free length, got %d", code)
+	}
+	chirp := postChirp(t, server, walt, "Say my name")
+	postChirp(t, server, walt, "Heisenberg")
+	if code := post(walt, "You're goddamn right"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 past the free daily quota, got %d", code)
+	}
+	edit := map[string]string{"body": "Say my name."}
+	if res := doJSON(t, "PUT", url+"/"+chirp.ID.String(), walt.Token, edit, nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 editing on the free tier, got %d", res.StatusCode)
+	}
+
+	upgrade(t, server, walt)
+	if code := post(walt, strings.Repeat("a", 280)); code != http.StatusCreated {
+		t.Errorf("expected a Red chirp up to 280 long, got %d", code)
+	}
+	if code := post(walt, strings.Repeat("a", 281)); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a chirp over the Red length, got %d", code)
+	}
+	if code := post(walt, "You're goddamn right"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 past the Red daily quota, got %d", code)
+	}
+	var edited Chirp
+	if res := doJSON(t, "PUT", url+"/"+chirp.ID.String(), walt.Token, edit, &edited); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 editing on the Red tier, got %d", res.StatusCode)
+	}
+	if edited.Body != "Say my name." || !edited.Edited {
+		t.Errorf("unexpected edited chirp %+v", edited)
+	}
+}
+
+func TestChirpLength(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	url := server.URL + "/api/chirps"
+
+	// Each of these is two code points and eight bytes, but one character
+	postChirp(t, server, walt, strings.Repeat("👍🏽", 140))
+	postChirp(t, server, walt, "Read https://example.com/"+strings.Repeat("a", 200)+" for the recipe")
+
+	var tooLong struct {
+		Error  string `json:"error"`
+		Length int    `json:"length"`
+		Limit  int    `json:"limit"`
+	}
+	res := doJSON(t, "POST", url, walt.Token, map[string]string{"body": strings.Repeat("👍🏽", 141)}, &tooLong)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a long chirp, got %d", res.StatusCode)
+	}
+	if tooLong.Length != 141 || tooLong.Limit != 140 || tooLong.Error == "" {
+		t.Errorf("expected the length and limit in the error, got %+v", tooLong)
+	}
+
+	if chirp := postChirp(t, server, walt, "Café Heisenberg"); chirp.Body != "Café Heisenberg" {
+		t.Errorf("expected the body to be stored in NFC, got %q", chirp.Body)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowPage is one page of a follower or following listing, with cursors
+// like ChirpPage.
+type FollowPage struct {
+	Follows    []Follow `json:"follows"`
+	NextCursor *string  `json:"next_cursor"`
+	PrevCursor *string  `json:"prev_cursor"`
+}
+
+func (cfg *apiConfig) handleFollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse userID", err)
+		return
+	}
+
+
+	userID := requestUserID(r)
+
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+
+	if _, err := cfg.db.GetUserByID(r.Context(), followeeID); err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+
+	err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+func (cfg *apiConfig) handleUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse userID", err)
+		return
+	}
+
+
+	userID := requestUserID(r)
+
+
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+// handleGetFollowers lists the users following {userID}, newest first.
+func (cfg *apiConfig) handleGetFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(f database.Follow) uuid.UUID { return f.FollowerID },
+		func(page pageRequest, userID uuid.UUID) ([]database.Follow, error) {
+			cursorCreatedAt, cursorID := page.cursorParams()
+			if page.backward() {
+				return cfg.db.ListFollowersAfter(r.Context(), database.ListFollowersAfterParams{
+					UserID:          userID,
+					CursorCreatedAt: cursorCreatedAt,
+					CursorID:        cursorID,
+					PageSize:        page.Limit + 1,
+				})
+			}
+			return cfg.db.ListFollowersBefore(r.Context(), database.ListFollowersBeforeParams{
+				UserID:          userID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID:        cursorID,
+				PageSize:        page.Limit + 1,
+			})
+		})
+}
+
+
+// handleGetFollowing lists the users {userID} follows, newest first.
+func (cfg *apiConfig) handleGetFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(f database.Follow) uuid.UUID { return f.FolloweeID },
+		func(page pageRequest, userID uuid.UUID) ([]database.Follow, error) {
+			cursorCreatedAt, cursorID := page.cursorParams()
+			if page.backward() {
+				return cfg.db.ListFollowingAfter(r.Context(), database.ListFollowingAfterParams{
+					UserID:          userID,
+					CursorCreatedAt: cursorCreatedAt,
+					CursorID:        cursorID,
+					PageSize:        page.Limit + 1,
+				})
+			}
+			return cfg.db.ListFollowingBefore(r.Context(), database.ListFollowingBeforeParams{
+				UserID:          userID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID:        cursorID,
+				PageSize:        page.Limit + 1,
+			})
+		})
+}
+
+
+// listFollows does the shared work of the follower/following listings.
+// other picks the user on the far side of each follow row.
+func (cfg *apiConfig) listFollows(
+	w http.ResponseWriter,
+	r *http.Request,
+	other func(database.Follow) uuid.UUID,
+	fetch func(pageRequest, uuid.UUID) ([]database.Follow, error),
+) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse userID", err)
+		return
+	}
+
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+
+	if _, err := cfg.db.GetUserByID(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+
+
+	rows, err := fetch(page, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve follows", err)
+		return
+	}
+	rows, hasMore := trimPage(page, rows)
+
+	follows := []Follow{}
+	for _, row := range rows {
+		follows = append(follows, Follow{
+			UserID:     other(row),
+			FollowedAt: row.CreatedAt,
+		})
+	}
+
+	next, prev := pageBounds(page, follows, hasMore, func(f Follow) pageCursor {
+		return pageCursor{CreatedAt: f.FollowedAt, ID: f.UserID}
+	})
+	response := FollowPage{
+		Follows:    follows,
+		NextCursor: cursorString(next),
+		PrevCursor: cursorString(prev),
+	}
+	setPageLinks(w, r, response.NextCursor, response.PrevCursor)
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+
+// handleGetTimeline returns chirps from the authors the caller follows,
+// newest first.
+func (cfg *apiConfig) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID := requestUserID(r)
+
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var chirps []database.Chirp
+	if page.backward() {
+		chirps, err = cfg.db.GetTimelineAfter(r.Context(), database.GetTimelineAfterParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        page.Limit + 1,
+		})
+	} else {
+		chirps, err = cfg.db.GetTimelineBefore(r.Context(), database.GetTimelineBeforeParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        page.Limit + 1,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve timeline", err)
+		return
+	}
+
+	chirps, hasMore := trimPage(page, chirps)
+	timeline := chirpsFromDB(chirps)
+	if err := cfg.markLikedByMe(r.Context(), timeline, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
+		return
+	}
+
+	next, prev := pageBounds(page, timeline, hasMore, chirpPosition)
+	response := ChirpPage{
+		Chirps:     timeline,
+		NextCursor: cursorString(next),
+		PrevCursor: cursorString(prev),
+	}
+	setPageLinks(w, r, response.NextCursor, response.PrevCursor)
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFollowUser(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+	follow := server.URL + "/api/users/" + jesse.ID.String() + "/follow"
+
+	if res := doJSON(t, "POST", follow, "", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/users/"+walt.ID.String()+"/follow", walt.Token, nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 following yourself, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/users/"+uuid.NewString()+"/follow", walt.Token, nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 following an unknown user, got %d", res.StatusCode)
+	}
+
+	// Following twice is the same as following once
+	for range 2 {
+		if res := doJSON(t, "POST", follow, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204 following, got %d", res.StatusCode)
+		}
+	}
+	var followers FollowPage
+	doJSON(t, "GET", server.URL+"/api/users/"+jesse.ID.String()+"/followers", "", nil, &followers)
+	if len(followers.Follows) != 1 || followers.Follows[0].UserID != walt.ID {
+		t.Errorf("expected walt as the only follower, got %+v", followers.Follows)
+	}
+	var following FollowPage
+	doJSON(t, "GET", server.URL+"/api/users/"+walt.ID.String()+"/following", "", nil, &following)
+	if len(following.Follows) != 1 || following.Follows[0].UserID != jesse.ID {
+		t.Errorf("expected walt to follow only jesse, got %+v", following.Follows)
+	}
+
+	if res := doJSON(t, "DELETE", follow, "", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 unfollowing without a token, got %d", res.StatusCode)
+	}
+	// Unfollowing is idempotent too, so the second time is a no-op
+	for range 2 {
+		if res := doJSON(t, "DELETE", follow, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204 unfollowing, got %d", res.StatusCode)
+		}
+	}
+	followers = FollowPage{}
+	doJSON(t, "GET", server.URL+"/api/users/"+jesse.ID.String()+"/followers", "", nil, &followers)
+	if followers.Follows == nil || len(followers.Follows) != 0 {
+		t.Errorf("expected no followers left, got %+v", followers.Follows)
+	}
+}
+
+func TestListFollowsPages(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	var fans []testUser
+	for _, email := range []string{"jesse@breakingbad.com", "skyler@breakingbad.com", "hank@breakingbad.com"} {
+		fan := signUp(t, server, email)
+		if res := doJSON(t, "POST", server.URL+"/api/users/"+walt.ID.String()+"/follow", fan.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204 following, got %d", res.StatusCode)
+		}
+		fans = append(fans, fan)
+	}
+	followers := server.URL + "/api/users/" + walt.ID.String() + "/followers?limit=2"
+
+	// Newest first
+	var page FollowPage
+	res := doJSON(t, "GET", followers, "", nil, &page)
+	if len(page.Follows) != 2 || page.Follows[0].UserID != fans[2].ID || page.Follows[1].UserID != fans[1].ID {
+		t.Fatalf("unexpected first page %+v", page.Follows)
+	}
+	if page.NextCursor == nil || *page.NextCursor != res.Header.Get("X-Next-Cursor") || page.PrevCursor != nil {
+		t.Fatalf("expected only a next cursor, matching the header, got %+v", page)
+	}
+
+	next := *page.NextCursor
+	page = FollowPage{}
+	doJSON(t, "GET", followers+"&cursor="+url.QueryEscape(next), "", nil, &page)
+	if len(page.Follows) != 1 || page.Follows[0].UserID != fans[0].ID || page.NextCursor != nil || page.PrevCursor == nil {
+		t.Errorf("unexpected last page %+v", page)
+	}
+
+	if res := doJSON(t, "GET", server.URL+"/api/users/not-a-uuid/followers", "", nil, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed id, got %d", res.StatusCode)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+	saul := signUp(t, server, "saul@breakingbad.com")
+	timeline := server.URL + "/api/timeline"
+
+	if res := doJSON(t, "GET", timeline, "", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+
+	for _, user := range []testUser{jesse, saul} {
+		if res := doJSON(t, "POST", server.URL+"/api/users/"+user.ID.String()+"/follow", walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204 following, got %d", res.StatusCode)
+		}
+	}
+	first := postChirp(t, server, jesse, "Yeah, science")
+	postChirp(t, server, walt, "Say my name")
+	second := postChirp(t, server, saul, "Better call Saul")
+	third := postChirp(t, server, jesse, "Yo")
+
+	// Only followed authors, newest first
+	var page ChirpPage
+	res := doJSON(t, "GET", timeline+"?limit=2", walt.Token, nil, &page)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if len(page.Chirps) != 2 || page.Chirps[0].ID != third.ID || page.Chirps[1].ID != second.ID {
+		t.Fatalf("unexpected first page %+v", page.Chirps)
+	}
+	if page.NextCursor == nil || *page.NextCursor != res.Header.Get("X-Next-Cursor") || page.PrevCursor != nil {
+		t.Fatalf("expected only a next cursor, matching the header, got %+v", page)
+	}
+
+	next := *page.NextCursor
+	page = ChirpPage{}
+	doJSON(t, "GET", timeline+"?limit=2&cursor="+url.QueryEscape(next), walt.Token, nil, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0].ID != first.ID || page.NextCursor != nil || page.PrevCursor == nil {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	// And back again
+	prev := *page.PrevCursor
+	page = ChirpPage{}
+	doJSON(t, "GET", timeline+"?limit=2&cursor="+url.QueryEscape(prev), walt.Token, nil, &page)
+	if len(page.Chirps) != 2 || page.Chirps[0].ID != third.ID || page.Chirps[1].ID != second.ID {
+		t.Errorf("expected the previous page to be the first one again, got %+v", page.Chirps)
+	}
+
+	page = ChirpPage{}
+	doJSON(t, "GET", timeline, jesse.Token, nil, &page)
+	if page.Chirps == nil || len(page.Chirps) != 0 {
+		t.Errorf("expected an empty timeline for someone following no one, got %+v", page.Chirps)
+	}
+}
@@ -2,13 +2,13 @@ package auth
 
 import (
 	"golang.org/x/crypto/bcrypt"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"time"
 	"errors"
 	"strings"
 	"net/http"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 )
@@ -25,50 +25,14 @@ func CheckPasswordHash(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// MakeJWT signs an HS256 access token with tokenSecret.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims {
-		Issuer:		"chirpy",
-		IssuedAt:	jwt.NewNumericDate(time.Now()),
-		ExpiresAt:	jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:	userID.String(),
-	})
-
-	signed_token, err := token.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return signed_token, nil
+	return NewKeySet(tokenSecret).MakeJWT(userID, expiresIn)
 }
 
-// ValidateJWT parses and validates a JWT, returning the user ID if valid.
+// ValidateJWT parses and validates an HS256 JWT, returning the user ID if valid.
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Ensure the signing method is what we expect
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(tokenSecret), nil
-	})
-
-	if err != nil {
-		return uuid.UUID{}, err
-	}
-
-	// Check if the token is valid and not expired
-	if !token.Valid {
-		return uuid.UUID{}, errors.New("invalid token")
-	}
-
-	// Parse the user ID from the Subject field
-	userID, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.UUID{}, errors.New("invalid user ID in token")
-	}
-
-	return userID, nil
+	return NewKeySet(tokenSecret).ValidateJWT(tokenString)
 }
 
 
@@ -98,6 +62,15 @@ func MakeRefreshToken() (string, error) {
 }
 
 
+// HashRefreshToken returns the digest refresh tokens are stored and looked
+// up by. The tokens carry 256 bits of randomness, so a plain SHA-256 is
+// enough; there's nothing to brute-force.
+func HashRefreshToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+
 func GetAPIKey(headers http.Header) (string, error) {
 	auth_header := headers.Get("Authorization")
 	if auth_header == "" {
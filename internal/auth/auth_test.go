@@ -112,3 +112,32 @@ func TestCheckPasswordHash(t *testing.T) {
 		t.Fatal("expected error for incorrect password, but got none")
 	}
 }
+
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("failed to make refresh token: %v", err)
+	}
+
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Fatal("hashed refresh token should not match the raw token")
+	}
+	if len(hash) != 64 {
+		t.Fatalf("expected a 64 character hex digest, got %d characters", len(hash))
+	}
+
+	// Lookups depend on the digest being stable
+	if HashRefreshToken(token) != hash {
+		t.Fatal("hashing the same token twice gave different digests")
+	}
+
+	other, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("failed to make refresh token: %v", err)
+	}
+	if HashRefreshToken(other) == hash {
+		t.Fatal("different tokens should not share a digest")
+	}
+}
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// KeySet holds the key access tokens are signed with and every key they
+// are accepted from. Asymmetric keys are identified by the kid header;
+// tokens without a kid fall back to the HS256 shared secret, if one is set.
+type KeySet struct {
+	hmacSecret []byte
+	signingKey *jwtKey
+	keys       map[string]*jwtKey
+}
+
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// JWK is a public key in RFC 7517 form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is the document served from /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet returns a key set that signs with HS256 using hmacSecret until
+// an asymmetric signing key is set. An empty secret disables HS256.
+func NewKeySet(hmacSecret string) *KeySet {
+	return &KeySet{
+		hmacSecret: []byte(hmacSecret),
+		keys:       map[string]*jwtKey{},
+	}
+}
+
+// SetSigningKey parses a PEM encoded Ed25519 or RSA private key and uses it
+// to sign new tokens. Its public half is also accepted for verification.
+func (ks *KeySet) SetSigningKey(pemData []byte) error {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return errors.New("signing key is not PEM encoded")
+	}
+
+	var private crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return fmt.Errorf("unsupported signing key PEM type %q", block.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't parse signing key: %w", err)
+	}
+
+	var public crypto.PublicKey
+	switch k := private.(type) {
+	case ed25519.PrivateKey:
+		public = k.Public()
+	case *rsa.PrivateKey:
+		public = k.Public()
+	default:
+		return fmt.Errorf("unsupported signing key type %T", private)
+	}
+
+	key, err := newJWTKey(public)
+	if err != nil {
+		return err
+	}
+	key.private = private
+
+	ks.signingKey = key
+	ks.keys[key.id] = key
+	return nil
+}
+
+// AddVerificationKey parses a PEM encoded Ed25519 or RSA public key and
+// accepts tokens signed by it. Keep retired signing keys here until every
+// token they issued has expired.
+func (ks *KeySet) AddVerificationKey(pemData []byte) error {
+	block, _ := pem.Decode(pemData)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return errors.New("verification key is not a PEM encoded PUBLIC KEY")
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("couldn't parse verification key: %w", err)
+	}
+
+	key, err := newJWTKey(public)
+	if err != nil {
+		return err
+	}
+	if _, ok := ks.keys[key.id]; !ok {
+		ks.keys[key.id] = key
+	}
+	return nil
+}
+
+// newJWTKey picks the signing method for a public key and derives its kid
+// from a SHA-256 of the key's PKIX encoding, so the same key always gets
+// the same kid on every replica.
+func newJWTKey(public crypto.PublicKey) (*jwtKey, error) {
+	var method jwt.SigningMethod
+	switch k := public.(type) {
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(der)
+
+	return &jwtKey{
+		id:     base64.RawURLEncoding.EncodeToString(digest[:16]),
+		method: method,
+		public: public,
+	}, nil
+}
+
+// MakeJWT issues an access token for userID.
+func (ks *KeySet) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		Subject:   userID.String(),
+	}
+
+	if ks.signingKey != nil {
+		token := jwt.NewWithClaims(ks.signingKey.method, claims)
+		token.Header["kid"] = ks.signingKey.id
+		return token.SignedString(ks.signingKey.private)
+	}
+
+	if len(ks.hmacSecret) == 0 {
+		return "", errors.New("no JWT signing key configured")
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+}
+
+// Claims are the validated contents of an access token.
+type Claims struct {
+	UserID uuid.UUID
+	jwt.RegisteredClaims
+}
+
+// ParseJWT parses and validates a JWT, returning its claims if valid.
+func (ks *KeySet) ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, &claims.RegisteredClaims, ks.keyFunc,
+		jwt.WithValidMethods([]string{"EdDSA", "RS256", "HS256"}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the token is valid and not expired
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// Parse the user ID from the Subject field
+	claims.UserID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, errors.New("invalid user ID in token")
+	}
+
+	return claims, nil
+}
+
+// ValidateJWT parses and validates a JWT, returning the user ID if valid.
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	claims, err := ks.ParseJWT(tokenString)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return claims.UserID, nil
+}
+
+// keyFunc finds the verification key for a token. The algorithm in the
+// header must match the key it names so one kind of key can't be passed
+// off as another.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, hasKid := token.Header["kid"].(string)
+	if !hasKid {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(ks.hmacSecret) == 0 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return ks.hmacSecret, nil
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+// JWKS lists the public keys tokens can be verified with. The HS256
+// secret is never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		jwk := JWK{
+			KeyID:     key.id,
+			Algorithm: key.method.Alg(),
+			Use:       "sig",
+		}
+		switch k := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	slices.SortFunc(set.Keys, func(a, b JWK) int { return strings.Compare(a.KeyID, b.KeyID) })
+	return set
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func generateKeyPEMs(t *testing.T, alg string) (privatePEM, publicPEM []byte) {
+	t.Helper()
+
+	var private crypto.Signer
+	switch alg {
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate Ed25519 key: %v", err)
+		}
+		private = key
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("failed to generate RSA key: %v", err)
+		}
+		private = key
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+}
+
+func TestAsymmetricJWT(t *testing.T) {
+	for _, alg := range []string{"EdDSA", "RS256"} {
+		t.Run(alg, func(t *testing.T) {
+			privatePEM, _ := generateKeyPEMs(t, alg)
+
+			keys := NewKeySet("")
+			if err := keys.SetSigningKey(privatePEM); err != nil {
+				t.Fatalf("failed to set signing key: %v", err)
+			}
+
+			userID := uuid.New()
+			token, err := keys.MakeJWT(userID, time.Minute)
+			if err != nil {
+				t.Fatalf("failed to create JWT: %v", err)
+			}
+
+			parsedUserID, err := keys.ValidateJWT(token)
+			if err != nil {
+				t.Fatalf("failed to validate JWT: %v", err)
+			}
+			if parsedUserID != userID {
+				t.Errorf("expected userID %s, got %s", userID, parsedUserID)
+			}
+
+			jwks := keys.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Algorithm != alg {
+				t.Fatalf("expected one %s key in the JWKS, got %+v", alg, jwks.Keys)
+			}
+		})
+	}
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := generateKeyPEMs(t, "RS256")
+	newPrivate, _ := generateKeyPEMs(t, "EdDSA")
+
+	oldKeys := NewKeySet("")
+	if err := oldKeys.SetSigningKey(oldPrivate); err != nil {
+		t.Fatalf("failed to set signing key: %v", err)
+	}
+	oldToken, err := oldKeys.MakeJWT(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create JWT: %v", err)
+	}
+
+	// Without the old public key the token is from an unknown key
+	rotated := NewKeySet("")
+	if err := rotated.SetSigningKey(newPrivate); err != nil {
+		t.Fatalf("failed to set signing key: %v", err)
+	}
+	if _, err := rotated.ValidateJWT(oldToken); err == nil {
+		t.Fatal("expected error for a token from an unknown key, got nil")
+	}
+
+	if err := rotated.AddVerificationKey(oldPublic); err != nil {
+		t.Fatalf("failed to add verification key: %v", err)
+	}
+	if _, err := rotated.ValidateJWT(oldToken); err != nil {
+		t.Fatalf("expected token from the retired key to validate: %v", err)
+	}
+	if got := len(rotated.JWKS().Keys); got != 2 {
+		t.Fatalf("expected both keys in the JWKS, got %d", got)
+	}
+}
+
+func TestHMACTokenRejectedWithoutSecret(t *testing.T) {
+	token, err := MakeJWT(uuid.New(), "shared-secret", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create JWT: %v", err)
+	}
+
+	privatePEM, _ := generateKeyPEMs(t, "EdDSA")
+	keys := NewKeySet("")
+	if err := keys.SetSigningKey(privatePEM); err != nil {
+		t.Fatalf("failed to set signing key: %v", err)
+	}
+
+	if _, err := keys.ValidateJWT(token); err == nil {
+		t.Fatal("expected error for an HS256 token with no secret configured, got nil")
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeaders(signature string, timestamp time.Time) http.Header {
+	headers := http.Header{}
+	headers.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	headers.Set(WebhookSignatureHeader, signature)
+	return headers
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"event":"user.upgraded"}`)
+	secrets := []string{"new-secret", "old-secret"}
+	tolerance := 5 * time.Minute
+
+	cases := map[string]struct {
+		headers http.Header
+		body    []byte
+		err     error
+	}{
+		"current secret": {
+			headers: signedHeaders(SignWebhook("new-secret", now, body), now),
+			body:    body,
+		},
+		"secret being rotated out": {
+			headers: signedHeaders(SignWebhook("old-secret", now, body), now),
+			body:    body,
+		},
+		"one of several signatures": {
+			headers: signedHeaders(SignWebhook("unknown", now, body)+", "+SignWebhook("new-secret", now, body), now),
+			body:    body,
+		},
+		"slightly in the future": {
+			headers: signedHeaders(SignWebhook("new-secret", now.Add(time.Minute), body), now.Add(time.Minute)),
+			body:    body,
+		},
+		"unknown secret": {
+			headers: signedHeaders(SignWebhook("unknown", now, body), now),
+			body:    body,
+			err:     ErrWebhookBadSignature,
+		},
+		"tampered body": {
+			headers: signedHeaders(SignWebhook("new-secret", now, body), now),
+			body:    []byte(`{"event":"user.downgraded"}`),
+			err:     ErrWebhookBadSignature,
+		},
+		"timestamp swapped after signing": {
+			headers: signedHeaders(SignWebhook("new-secret", now.Add(-time.Hour), body), now),
+			body:    body,
+			err:     ErrWebhookBadSignature,
+		},
+		"replayed later": {
+			headers: signedHeaders(SignWebhook("new-secret", now.Add(-time.Hour), body), now.Add(-time.Hour)),
+			body:    body,
+			err:     ErrWebhookStale,
+		},
+		"unsigned": {
+			headers: http.Header{},
+			body:    body,
+			err:     ErrWebhookUnsigned,
+		},
+		"malformed signature": {
+			headers: signedHeaders("v1=not-hex", now),
+			body:    body,
+			err:     ErrWebhookBadSignature,
+		},
+	}
+	for name, tc := range cases {
+		err := VerifyWebhook(tc.headers, tc.body, secrets, tolerance, now)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package chirptext measures chirp bodies the way a reader sees them. A
+// chirp's length is the number of user-perceived characters (grapheme
+// clusters), so an emoji built from several code points counts once, and
+// every link counts as URLLength however long it is.
+package chirptext
+
+import (
+	"regexp"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLLength is what a link counts as towards a chirp's length.
+const URLLength = 23
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://\S+`)
+
+// Normalize puts body in Unicode normalization form C, so text that looks
+// the same is stored, searched and moderated the same way.
+func Normalize(body string) string {
+	return norm.NFC.String(body)
+}
+
+// Length is the length of body as it counts against a chirp limit. body
+// should already be normalized.
+func Length(body string) int {
+	n, last := 0, 0
+	for _, link := range urlPattern.FindAllStringIndex(body, -1) {
+		n += uniseg.GraphemeClusterCount(body[last:link[0]]) + URLLength
+		last = link[1]
+	}
+	return n + uniseg.GraphemeClusterCount(body[last:])
+}
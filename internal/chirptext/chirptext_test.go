@@ -0,0 +1,42 @@
+package chirptext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		want int
+	}{
+		"ascii":                {"I am the one who knocks", 23},
+		"empty":                {"", 0},
+		"accented":             {"café", 4},
+		"emoji":                {strings.Repeat("😀", 50), 50},
+		"family emoji":         {"👨‍👩‍👧‍👦", 1},
+		"flag":                 {"🇦🇷", 1},
+		"skin tone":            {"👍🏽", 1},
+		"combining mark":       {"é", 1},
+		"link":                 {"https://example.com/" + strings.Repeat("a", 200), URLLength},
+		"link in text":         {"see http://a.io now", 4 + URLLength + 4},
+		"two links":            {"https://a.io https://b.io", 2*URLLength + 1},
+		"scheme is required":   {"example.com", 11},
+		"uppercase scheme":     {"HTTPS://EXAMPLE.COM", URLLength},
+		"scheme inside a word": {"xhttps://a.io", 13},
+	}
+	for name, tc := range cases {
+		if got := Length(tc.body); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, got)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("café"); got != "café" {
+		t.Errorf("expected the composed form, got %q", got)
+	}
+	if got := Normalize("café"); got != "café" {
+		t.Errorf("expected composed text to be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,407 @@
+// Package config loads Chirpy's settings. Values come from, in increasing
+// order of precedence: built-in defaults, a YAML file, environment
+// variables and command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is everything the server needs at startup.
+type Config struct {
+	Port         string `yaml:"port"`
+	FilepathRoot string `yaml:"filepath_root"`
+	// Platform is dev or prod. Destructive admin endpoints only work in dev.
+	Platform string `yaml:"platform"`
+	LogLevel string `yaml:"log_level"`
+	DBURL    string `yaml:"db_url"`
+	// MigrateOnStart applies pending migrations before the server starts
+	// listening.
+	MigrateOnStart bool `yaml:"migrate_on_start"`
+	// PublishInterval is how often chirps scheduled for later are checked
+	// for being due.
+	PublishInterval time.Duration `yaml:"publish_interval"`
+
+	JWT           JWTConfig           `yaml:"jwt"`
+	Server        ServerConfig        `yaml:"server"`
+	Polka         PolkaConfig         `yaml:"polka"`
+	Subscriptions SubscriptionConfig  `yaml:"subscriptions"`
+	Tiers         TiersConfig         `yaml:"tiers"`
+	Mail          MailConfig          `yaml:"mail"`
+	PasswordReset PasswordResetConfig `yaml:"password_reset"`
+
+	AdminAPIKey         string `yaml:"admin_api_key"`
+	ModerationRulesFile string `yaml:"moderation_rules_file"`
+}
+
+// JWTConfig picks the keys access tokens are signed with. Tokens are
+// signed with SigningKeyFile when it is set and with Secret (HS256)
+// otherwise.
+type JWTConfig struct {
+	Secret               string   `yaml:"secret"`
+	SigningKeyFile       string   `yaml:"signing_key_file"`
+	VerificationKeyFiles []string `yaml:"verification_key_files"`
+}
+
+// PolkaConfig verifies webhooks from Polka, the payment provider. A
+// delivery must be signed with one of Secrets, and more than one is
+// accepted so a secret can be rotated without dropping events. Deliveries
+// signed more than Tolerance before or after now are refused as replays.
+// With no secrets every delivery is refused.
+type PolkaConfig struct {
+	Secrets   []string      `yaml:"secrets"`
+	Tolerance time.Duration `yaml:"tolerance"`
+}
+
+// SubscriptionConfig governs Chirpy Red subscriptions. A subscription
+// that isn't renewed keeps its perks for GracePeriod after it expires, and
+// lapsed ones are looked for every CheckInterval.
+type SubscriptionConfig struct {
+	GracePeriod   time.Duration `yaml:"grace_period"`
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// TiersConfig is the policy table for the account tiers: what free users
+// and Chirpy Red subscribers may do.
+type TiersConfig struct {
+	Free TierPolicy `yaml:"free"`
+	Red  TierPolicy `yaml:"red"`
+}
+
+// For returns the policy of a user who is or isn't Chirpy Red.
+func (t TiersConfig) For(isChirpyRed bool) TierPolicy {
+	if isChirpyRed {
+		return t.Red
+	}
+	return t.Free
+}
+
+// TierPolicy is what one tier allows. DailyChirps caps the chirps posted
+// in any 24 hours, with 0 meaning no cap; MaxScheduled caps the chirps
+// waiting to be published, with 0 meaning the tier can't schedule.
+type TierPolicy struct {
+	MaxChirpLength int  `yaml:"max_chirp_length"`
+	DailyChirps    int  `yaml:"daily_chirps"`
+	CanEdit        bool `yaml:"can_edit"`
+	MaxScheduled   int  `yaml:"max_scheduled"`
+}
+
+// MailConfig picks how email goes out: through SMTP when SMTP.Host is
+// set, otherwise into a file per message under Dir when that is set. With
+// neither, the dev platform only logs who mail was for, and prod sends no
+// mail at all. A message that takes longer than SendTimeout is given up on.
+type MailConfig struct {
+	From        string        `yaml:"from"`
+	SMTP        SMTPConfig    `yaml:"smtp"`
+	Dir         string        `yaml:"dir"`
+	SendTimeout time.Duration `yaml:"send_timeout"`
+}
+
+// SMTPConfig is the server mail is relayed through. Username and Password
+// are only sent when Username is set.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// PasswordResetConfig governs password reset emails. A reset token is
+// good for TokenTTL, and the emailed link is LinkURL with the token added
+// as a query parameter.
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	LinkURL  string        `yaml:"link_url"`
+}
+
+// ServerConfig bounds how long a client may take over each part of a
+// request, and how long a shutdown waits for in-flight requests.
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		Port:         "8080",
+		FilepathRoot: ".",
+		Platform:     "prod",
+		LogLevel:     "info",
+		Server: ServerConfig{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Polka: PolkaConfig{
+			Tolerance: 5 * time.Minute,
+		},
+		Subscriptions: SubscriptionConfig{
+			GracePeriod:   72 * time.Hour,
+			CheckInterval: time.Hour,
+		},
+		Tiers: TiersConfig{
+			Free: TierPolicy{
+				MaxChirpLength: 140,
+				DailyChirps:    50,
+			},
+			Red: TierPolicy{
+				MaxChirpLength: 280,
+				DailyChirps:    500,
+				CanEdit:        true,
+				MaxScheduled:   25,
+			},
+		},
+		Mail: MailConfig{
+			From:        "chirpy@localhost",
+			SMTP:        SMTPConfig{Port: 587},
+			SendTimeout: 30 * time.Second,
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTL: time.Hour,
+			LinkURL:  "http://localhost:8080/app/reset-password",
+		},
+		PublishInterval: 10 * time.Second,
+	}
+}
+
+// Load builds the configuration from args (without the program name) and
+// the environment as seen through getenv, and validates it. The file is
+// named by -config or CONFIG_FILE.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg, _, err := Parse(args, getenv)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Parse is Load without the validation. It also returns the arguments left
+// after the flags, such as a subcommand.
+func Parse(args []string, getenv func(string) string) (Config, []string, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", getenv("CONFIG_FILE"), "path to a YAML config file")
+	port := flags.String("port", "", "port to listen on")
+	root := flags.String("root", "", "directory served under /app/")
+	platform := flags.String("platform", "", "dev or prod")
+	logLevel := flags.String("log-level", "", "debug, info, warn or error")
+	dbURL := flags.String("db-url", "", "postgres:// URL or sqlite: database file")
+	migrateOnStart := flags.Bool("migrate-on-start", false, "apply pending migrations before serving")
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(getenv); err != nil {
+		return Config{}, nil, err
+	}
+
+	// Only flags that were actually passed override the file and env
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "root":
+			cfg.FilepathRoot = *root
+		case "platform":
+			cfg.Platform = *platform
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "db-url":
+			cfg.DBURL = *dbURL
+		case "migrate-on-start":
+			cfg.MigrateOnStart = *migrateOnStart
+		}
+	})
+
+	return cfg, flags.Args(), nil
+}
+
+func (cfg *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *Config) loadEnv(getenv func(string) string) error {
+	stringVars := map[string]*string{
+		"PORT":                  &cfg.Port,
+		"FILEPATH_ROOT":         &cfg.FilepathRoot,
+		"PLATFORM":              &cfg.Platform,
+		"LOG_LEVEL":             &cfg.LogLevel,
+		"DB_URL":                &cfg.DBURL,
+		"secret":                &cfg.JWT.Secret,
+		"JWT_SIGNING_KEY_FILE":  &cfg.JWT.SigningKeyFile,
+		"ADMIN_API_KEY":         &cfg.AdminAPIKey,
+		"MODERATION_RULES_FILE": &cfg.ModerationRulesFile,
+		"MAIL_FROM":             &cfg.Mail.From,
+		"MAIL_DIR":              &cfg.Mail.Dir,
+		"SMTP_HOST":             &cfg.Mail.SMTP.Host,
+		"SMTP_USERNAME":         &cfg.Mail.SMTP.Username,
+		"SMTP_PASSWORD":         &cfg.Mail.SMTP.Password,
+		"PASSWORD_RESET_URL":    &cfg.PasswordReset.LinkURL,
+	}
+	for name, field := range stringVars {
+		if value := getenv(name); value != "" {
+			*field = value
+		}
+	}
+
+	if value := getenv("JWT_VERIFICATION_KEY_FILES"); value != "" {
+		cfg.JWT.VerificationKeyFiles = splitList(value)
+	}
+	if value := getenv("POLKA_WEBHOOK_SECRETS"); value != "" {
+		cfg.Polka.Secrets = splitList(value)
+	}
+
+	if value := getenv("MIGRATE_ON_START"); value != "" {
+		migrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("MIGRATE_ON_START: %w", err)
+		}
+		cfg.MigrateOnStart = migrate
+	}
+	if value := getenv("SMTP_PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("SMTP_PORT: %w", err)
+		}
+		cfg.Mail.SMTP.Port = port
+	}
+
+	durations := map[string]*time.Duration{
+		"SERVER_READ_HEADER_TIMEOUT":  &cfg.Server.ReadHeaderTimeout,
+		"SERVER_READ_TIMEOUT":         &cfg.Server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":        &cfg.Server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":         &cfg.Server.IdleTimeout,
+		"SERVER_SHUTDOWN_TIMEOUT":     &cfg.Server.ShutdownTimeout,
+		"POLKA_WEBHOOK_TOLERANCE":     &cfg.Polka.Tolerance,
+		"SUBSCRIPTION_GRACE_PERIOD":   &cfg.Subscriptions.GracePeriod,
+		"SUBSCRIPTION_CHECK_INTERVAL": &cfg.Subscriptions.CheckInterval,
+		"PUBLISH_INTERVAL":            &cfg.PublishInterval,
+		"PASSWORD_RESET_TTL":          &cfg.PasswordReset.TokenTTL,
+		"MAIL_SEND_TIMEOUT":           &cfg.Mail.SendTimeout,
+	}
+	for name, field := range durations {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = d
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once.
+func (cfg Config) Validate() error {
+	var errs []error
+	if cfg.Port == "" {
+		errs = append(errs, errors.New("port must be set"))
+	}
+	if cfg.DBURL == "" {
+		errs = append(errs, errors.New("DB_URL must be set"))
+	}
+	if cfg.Platform != "dev" && cfg.Platform != "prod" {
+		errs = append(errs, fmt.Errorf("platform must be dev or prod, got %q", cfg.Platform))
+	}
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log level must be debug, info, warn or error, got %q", cfg.LogLevel))
+	}
+	if cfg.JWT.Secret == "" && cfg.JWT.SigningKeyFile == "" {
+		errs = append(errs, errors.New("either secret or JWT_SIGNING_KEY_FILE must be set"))
+	}
+
+	for _, timeout := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read header timeout", cfg.Server.ReadHeaderTimeout},
+		{"read timeout", cfg.Server.ReadTimeout},
+		{"write timeout", cfg.Server.WriteTimeout},
+		{"idle timeout", cfg.Server.IdleTimeout},
+		{"shutdown timeout", cfg.Server.ShutdownTimeout},
+		{"Polka webhook tolerance", cfg.Polka.Tolerance},
+		{"subscription check interval", cfg.Subscriptions.CheckInterval},
+		{"publish interval", cfg.PublishInterval},
+		{"password reset token TTL", cfg.PasswordReset.TokenTTL},
+		{"mail send timeout", cfg.Mail.SendTimeout},
+	} {
+		if timeout.d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", timeout.name, timeout.d))
+		}
+	}
+	if cfg.Subscriptions.GracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("subscription grace period can't be negative, got %s", cfg.Subscriptions.GracePeriod))
+	}
+	if cfg.Mail.SMTP.Host != "" && (cfg.Mail.SMTP.Port <= 0 || cfg.Mail.SMTP.Port > 65535) {
+		errs = append(errs, fmt.Errorf("SMTP port must be between 1 and 65535, got %d", cfg.Mail.SMTP.Port))
+	}
+	if cfg.PasswordReset.LinkURL == "" {
+		errs = append(errs, errors.New("password reset link URL must be set"))
+	}
+	for _, tier := range []struct {
+		name   string
+		policy TierPolicy
+	}{
+		{"free", cfg.Tiers.Free},
+		{"red", cfg.Tiers.Red},
+	} {
+		if tier.policy.MaxChirpLength <= 0 {
+			errs = append(errs, fmt.Errorf("%s tier max chirp length must be positive, got %d", tier.name, tier.policy.MaxChirpLength))
+		}
+		if tier.policy.DailyChirps < 0 || tier.policy.MaxScheduled < 0 {
+			errs = append(errs, fmt.Errorf("%s tier limits can't be negative", tier.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
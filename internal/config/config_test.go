@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envFunc(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chirpy.yaml")
+	file := `
+port: "9000"
+platform: dev
+db_url: postgres://file
+jwt:
+  secret: from-file
+server:
+  write_timeout: 1m
+polka:
+  tolerance: 30s
+subscriptions:
+  grace_period: 24h
+tiers:
+  red:
+    max_chirp_length: 500
+mail:
+  smtp:
+    host: smtp.file
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load([]string{"-config", path, "-port", "7000"}, envFunc(map[string]string{
+		"DB_URL":                     "postgres://env",
+		"JWT_VERIFICATION_KEY_FILES": "a.pem, b.pem",
+		"POLKA_WEBHOOK_SECRETS":      "new-secret,old-secret",
+		"SMTP_PORT":                  "2525",
+		"PASSWORD_RESET_TTL":         "15m",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Port != "7000" {
+		t.Errorf("expected the flag to win, got port %q", cfg.Port)
+	}
+	if cfg.DBURL != "postgres://env" {
+		t.Errorf("expected the env to beat the file, got %q", cfg.DBURL)
+	}
+	if cfg.Platform != "dev" || cfg.JWT.Secret != "from-file" {
+		t.Errorf("expected file values, got platform %q secret %q", cfg.Platform, cfg.JWT.Secret)
+	}
+	if cfg.Server.WriteTimeout != time.Minute || cfg.Server.ReadTimeout != Default().Server.ReadTimeout {
+		t.Errorf("unexpected timeouts %+v", cfg.Server)
+	}
+	if strings.Join(cfg.JWT.VerificationKeyFiles, "|") != "a.pem|b.pem" {
+		t.Errorf("unexpected verification keys %q", cfg.JWT.VerificationKeyFiles)
+	}
+	if strings.Join(cfg.Polka.Secrets, "|") != "new-secret|old-secret" || cfg.Polka.Tolerance != 30*time.Second {
+		t.Errorf("unexpected Polka settings %+v", cfg.Polka)
+	}
+	if cfg.Subscriptions.GracePeriod != 24*time.Hour || cfg.Subscriptions.CheckInterval != Default().Subscriptions.CheckInterval {
+		t.Errorf("unexpected subscription settings %+v", cfg.Subscriptions)
+	}
+	if cfg.Tiers.Red.MaxChirpLength != 500 || !cfg.Tiers.Red.CanEdit || cfg.Tiers.Free != Default().Tiers.Free {
+		t.Errorf("expected the file to override only the Red length, got %+v", cfg.Tiers)
+	}
+	if cfg.Mail.SMTP.Host != "smtp.file" || cfg.Mail.SMTP.Port != 2525 || cfg.Mail.From != Default().Mail.From {
+		t.Errorf("unexpected mail settings %+v", cfg.Mail)
+	}
+	if cfg.PasswordReset.TokenTTL != 15*time.Minute || cfg.PasswordReset.LinkURL != Default().PasswordReset.LinkURL {
+		t.Errorf("unexpected password reset settings %+v", cfg.PasswordReset)
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	_, err := Load(nil, envFunc(map[string]string{"PLATFORM": "staging"}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"DB_URL", "secret", "platform"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chirpy.yaml")
+	if err := os.WriteFile(path, []byte("db_ulr: postgres://typo\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := Load([]string{"-config", path}, envFunc(nil)); err == nil {
+		t.Fatal("expected an error for a misspelt key")
+	}
+}
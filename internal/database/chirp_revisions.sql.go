@@ -0,0 +1,93 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: chirp_revisions.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const listChirpRevisions = `-- name: ListChirpRevisions :many
+SELECT id, chirp_id, body, written_at, replaced_at FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC
+`
+
+func (q *Queries) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpRevisions, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpRevision
+	for rows.Next() {
+		var i ChirpRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChirpID,
+			&i.Body,
+			&i.WrittenAt,
+			&i.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+WITH previous AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, written_at, replaced_at)
+    SELECT
+        gen_random_uuid(),
+        current.id,
+        current.body,
+        COALESCE(current.edited_at, current.created_at),
+        NOW()
+    FROM (
+        SELECT id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at FROM chirps
+        WHERE chirps.id = $2 AND chirps.deleted_at IS NULL
+        FOR UPDATE
+    ) AS current
+)
+UPDATE chirps
+SET body = $1, updated_at = NOW(), edited_at = NOW()
+WHERE chirps.id = $2 AND chirps.deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at
+`
+
+type UpdateChirpBodyParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+// The current body is locked and copied into chirp_revisions in the same
+// statement, so concurrent edits can't lose a revision.
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.InReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.EditedAt,
+	)
+	return i, err
+}
@@ -7,29 +7,63 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const countChirpsSince = `-- name: CountChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND created_at > $2::timestamp
+`
+
+type CountChirpsSinceParams struct {
+	UserID uuid.NullUUID
+	Since  time.Time
+}
+
+// Deleted chirps still count, so deleting doesn't make room in a quota.
+func (q *Queries) CountChirpsSince(ctx context.Context, arg CountChirpsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsSince, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
-    gen_random_uuid(),
+WITH new_chirp AS (
+    SELECT gen_random_uuid() AS id
+)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, thread_id)
+SELECT
+    new_chirp.id,
     NOW(),
     NOW(),
     $1,
-    $2
-)
-RETURNING id, created_at, updated_at, body, user_id
+    $2,
+    $3,
+    COALESCE($4::uuid, new_chirp.id)
+FROM new_chirp
+RETURNING id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.NullUUID
+	Body        string
+	UserID      uuid.NullUUID
+	InReplyToID uuid.NullUUID
+	ThreadID    uuid.NullUUID
 }
 
+// Root chirps start their own thread, so the new id is generated up front
+// to be usable as thread_id too.
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyToID,
+		arg.ThreadID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,16 +71,33 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.InReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
 const deleteChirp = `-- name: DeleteChirp :one
-DELETE from chirps
-where id = $1
-RETURNING id, created_at, updated_at, body, user_id
+WITH revisions AS (
+    DELETE FROM chirp_revisions
+    WHERE chirp_revisions.chirp_id = $1
+), likes AS (
+    DELETE FROM chirp_likes
+    WHERE chirp_likes.chirp_id = $1
+)
+UPDATE chirps
+SET deleted_at = NOW(), updated_at = NOW(), body = ''
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at
 `
 
+// Old bodies and likes go with the chirp; the tombstone keeps nothing the
+// author wrote and no one's reaction to it. The chirp_likes_count trigger
+// takes like_count down to zero once the statement has run, so the
+// returned row still has the old count.
 func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 	row := q.db.QueryRowContext(ctx, deleteChirp, id)
 	var i Chirp
@@ -56,17 +107,58 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.InReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at ASC
+const getChirpByID = `-- name: GetChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByID, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.InReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const getChirpThreadID = `-- name: GetChirpThreadID :one
+SELECT thread_id FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpThreadID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getChirpThreadID, id)
+	var thread_id uuid.UUID
+	err := row.Scan(&thread_id)
+	return thread_id, err
+}
+
+const getThread = `-- name: GetThread :many
+SELECT id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at FROM chirps
+WHERE thread_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) GetThread(ctx context.Context, threadID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, threadID)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +172,11 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.LikeCount,
+			&i.InReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.EditedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -94,14 +191,32 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getAllChirpsByID = `-- name: GetAllChirpsByID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-WHERE user_id = $1
-ORDER BY created_at ASC
+const listChirpsAfter = `-- name: ListChirpsAfter :many
+SELECT id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetAllChirpsByID(ctx context.Context, userID uuid.NullUUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsByID, userID)
+type ListChirpsAfterParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsAfter(ctx context.Context, arg ListChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAfter,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +230,11 @@ func (q *Queries) GetAllChirpsByID(ctx context.Context, userID uuid.NullUUID) ([
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.LikeCount,
+			&i.InReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.EditedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -129,20 +249,151 @@ func (q *Queries) GetAllChirpsByID(ctx context.Context, userID uuid.NullUUID) ([
 	return items, nil
 }
 
-const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+const listChirpsBefore = `-- name: ListChirpsBefore :many
+SELECT id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
-func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirpByID, id)
-	var i Chirp
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
+type ListChirpsBeforeParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) ListChirpsBefore(ctx context.Context, arg ListChirpsBeforeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsBefore,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.InReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT
+    chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.like_count, chirps.in_reply_to_id, chirps.thread_id, chirps.deleted_at, chirps.edited_at,
+    ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    )::text AS highlight
+FROM chirps, to_tsquery('english', $1) AS query
+WHERE to_tsvector('english', chirps.body) @@ query
+AND chirps.deleted_at IS NULL
+AND ($2::uuid IS NULL OR chirps.user_id = $2::uuid)
+AND ($3::timestamp IS NULL OR chirps.created_at >= $3::timestamp)
+AND ($4::timestamp IS NULL OR chirps.created_at < $4::timestamp)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $6 OFFSET $5
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	AuthorID   uuid.NullUUID
+	Since      sql.NullTime
+	Until      sql.NullTime
+	PageOffset int32
+	PageSize   int32
+}
+
+type SearchChirpsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.NullUUID
+	LikeCount   int32
+	InReplyToID uuid.NullUUID
+	ThreadID    uuid.UUID
+	DeletedAt   sql.NullTime
+	EditedAt    sql.NullTime
+	Rank        float32
+	Highlight   string
+}
+
+// highlight is the body HTML-escaped, with the matches wrapped in <mark>
+// tags, so clients can render it as it is. The match has to use the same
+// to_tsvector('english', body) expression as the index from 006, or the
+// planner won't use it.
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.InReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.Rank,
+			&i.Highlight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
@@ -12,20 +12,87 @@ import (
 )
 
 type Chirp struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.NullUUID
+	LikeCount   int32
+	InReplyToID uuid.NullUUID
+	ThreadID    uuid.UUID
+	DeletedAt   sql.NullTime
+	EditedAt    sql.NullTime
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	WrittenAt  time.Time
+	ReplacedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type ModerationRule struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.NullUUID
+	Kind      string
+	Pattern   string
+	Action    string
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type PolkaEvent struct {
+	ID         string
+	Event      string
+	ReceivedAt time.Time
 }
 
 type RefreshToken struct {
-	Token     string
+	TokenHash      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	UserID         uuid.NullUUID
+	ExpiresAt      time.Time
+	RevokedAt      sql.NullTime
+	FamilyID       uuid.UUID
+	ReplacedByHash sql.NullString
+}
+
+type ScheduledChirp struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
+	UserID    uuid.UUID
+	Body      string
+	PublishAt time.Time
+}
+
+type Subscription struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	StartedAt time.Time
 	UpdatedAt time.Time
-	UserID    uuid.NullUUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	EndedAt   sql.NullTime
+	EndReason sql.NullString
 }
 
 type User struct {
@@ -0,0 +1,92 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: moderation_rules.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createModerationRule = `-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, kind, pattern, action)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (kind, pattern) DO NOTHING
+RETURNING id, created_at, kind, pattern, action
+`
+
+type CreateModerationRuleParams struct {
+	Kind    string
+	Pattern string
+	Action  string
+}
+
+// A rule that already exists returns no row.
+func (q *Queries) CreateModerationRule(ctx context.Context, arg CreateModerationRuleParams) (ModerationRule, error) {
+	row := q.db.QueryRowContext(ctx, createModerationRule, arg.Kind, arg.Pattern, arg.Action)
+	var i ModerationRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.Pattern,
+		&i.Action,
+	)
+	return i, err
+}
+
+const deleteModerationRule = `-- name: DeleteModerationRule :execrows
+DELETE FROM moderation_rules
+WHERE id = $1
+`
+
+func (q *Queries) DeleteModerationRule(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteModerationRule, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listModerationRules = `-- name: ListModerationRules :many
+SELECT id, created_at, kind, pattern, action FROM moderation_rules
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) ListModerationRules(ctx context.Context) ([]ModerationRule, error) {
+	rows, err := q.db.QueryContext(ctx, listModerationRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationRule
+	for rows.Next() {
+		var i ModerationRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Kind,
+			&i.Pattern,
+			&i.Action,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,77 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: password_reset_tokens.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    $3
+)
+`
+
+type CreatePasswordResetTokenParams struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createPasswordResetToken, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const resetPassword = `-- name: ResetPassword :one
+WITH claimed AS (
+    UPDATE password_reset_tokens
+    SET used_at = NOW()
+    WHERE password_reset_tokens.token_hash = $1
+    AND password_reset_tokens.used_at IS NULL
+    AND password_reset_tokens.expires_at > NOW()
+    RETURNING password_reset_tokens.user_id
+), others AS (
+    UPDATE password_reset_tokens
+    SET used_at = NOW()
+    WHERE password_reset_tokens.user_id IN (SELECT claimed.user_id FROM claimed)
+    AND password_reset_tokens.token_hash <> $1
+    AND password_reset_tokens.used_at IS NULL
+), updated AS (
+    UPDATE users
+    SET hashed_password = $2, updated_at = NOW()
+    WHERE users.id IN (SELECT claimed.user_id FROM claimed)
+), revoked AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(), updated_at = NOW()
+    WHERE refresh_tokens.user_id IN (SELECT claimed.user_id FROM claimed)
+    AND refresh_tokens.revoked_at IS NULL
+)
+SELECT claimed.user_id FROM claimed
+`
+
+type ResetPasswordParams struct {
+	TokenHash      string
+	HashedPassword string
+}
+
+// Uses up the token, along with every other unused token of its user, sets
+// the new password and revokes the user's refresh tokens so sessions
+// started with the old password end. Returns no rows when the token is
+// unknown, used or expired.
+func (q *Queries) ResetPassword(ctx context.Context, arg ResetPasswordParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, resetPassword, arg.TokenHash, arg.HashedPassword)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
@@ -0,0 +1,99 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	// Applies a Polka downgrade: records the event, ends the user's open
+	// subscription and takes away Chirpy Red, all or nothing. Affects no rows
+	// when the event was applied before or the user doesn't exist.
+	CancelSubscription(ctx context.Context, arg CancelSubscriptionParams) (int64, error)
+	ChangeEmailAndPassword(ctx context.Context, arg ChangeEmailAndPasswordParams) (User, error)
+	// Deleted chirps still count, so deleting doesn't make room in a quota.
+	CountChirpsSince(ctx context.Context, arg CountChirpsSinceParams) (int64, error)
+	CountScheduledChirps(ctx context.Context, userID uuid.UUID) (int64, error)
+	// Counts the user's scheduled chirps due strictly between after and before.
+	CountScheduledChirpsBetween(ctx context.Context, arg CountScheduledChirpsBetweenParams) (int64, error)
+	// Root chirps start their own thread, so the new id is generated up front
+	// to be usable as thread_id too.
+	CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error)
+	// A rule that already exists returns no row.
+	CreateModerationRule(ctx context.Context, arg CreateModerationRuleParams) (ModerationRule, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) (ScheduledChirp, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteAllUsers(ctx context.Context) error
+	// Old bodies and likes go with the chirp; the tombstone keeps nothing the
+	// author wrote and no one's reaction to it. The chirp_likes_count trigger
+	// takes like_count down to zero once the statement has run, so the
+	// returned row still has the old count.
+	DeleteChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
+	DeleteModerationRule(ctx context.Context, id uuid.UUID) (int64, error)
+	DeleteScheduledChirp(ctx context.Context, arg DeleteScheduledChirpParams) (int64, error)
+	// Ends every open subscription that expired before the cutoff and takes
+	// Chirpy Red away from its user.
+	ExpireSubscriptions(ctx context.Context, cutoff time.Time) ([]ExpireSubscriptionsRow, error)
+	FollowUser(ctx context.Context, arg FollowUserParams) error
+	GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
+	GetChirpThreadID(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// The user's subscription history, newest first.
+	GetSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error)
+	GetThread(ctx context.Context, threadID uuid.UUID) ([]Chirp, error)
+	GetTimelineAfter(ctx context.Context, arg GetTimelineAfterParams) ([]Chirp, error)
+	GetTimelineBefore(ctx context.Context, arg GetTimelineBeforeParams) ([]Chirp, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	LikeChirp(ctx context.Context, arg LikeChirpParams) error
+	ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error)
+	ListChirpsAfter(ctx context.Context, arg ListChirpsAfterParams) ([]Chirp, error)
+	ListChirpsBefore(ctx context.Context, arg ListChirpsBeforeParams) ([]Chirp, error)
+	ListFollowersAfter(ctx context.Context, arg ListFollowersAfterParams) ([]Follow, error)
+	ListFollowersBefore(ctx context.Context, arg ListFollowersBeforeParams) ([]Follow, error)
+	ListFollowingAfter(ctx context.Context, arg ListFollowingAfterParams) ([]Follow, error)
+	ListFollowingBefore(ctx context.Context, arg ListFollowingBeforeParams) ([]Follow, error)
+	ListLikedChirpIDs(ctx context.Context, arg ListLikedChirpIDsParams) ([]uuid.UUID, error)
+	ListModerationRules(ctx context.Context) ([]ModerationRule, error)
+	ListScheduledChirps(ctx context.Context, userID uuid.UUID) ([]ScheduledChirp, error)
+	// Moves every scheduled chirp whose time has come into chirps, each
+	// starting its own thread. Deleting and inserting in one statement means
+	// a chirp is published once even with several publishers running.
+	PublishDueChirps(ctx context.Context) ([]Chirp, error)
+	// Applies a Polka upgrade or renewal: records the event and then pushes
+	// back the expiry of the user's open subscription, or opens a new one, and
+	// makes the user Chirpy Red. The event is only recorded together with the
+	// change, so a delivery is either applied and remembered or neither. An
+	// expiry earlier than the current one is ignored, so a late redelivery
+	// can't shorten a subscription. Returns no rows when the event was applied
+	// before or the user doesn't exist.
+	RenewSubscription(ctx context.Context, arg RenewSubscriptionParams) (RenewSubscriptionRow, error)
+	// Uses up the token, along with every other unused token of its user, sets
+	// the new password and revokes the user's refresh tokens so sessions
+	// started with the old password end. Returns no rows when the token is
+	// unknown, used or expired.
+	ResetPassword(ctx context.Context, arg ResetPasswordParams) (uuid.UUID, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error)
+	// highlight is the body HTML-escaped, with the matches wrapped in <mark>
+	// tags, so clients can render it as it is. The match has to use the same
+	// to_tsvector('english', body) expression as the index from 006, or the
+	// planner won't use it.
+	SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error)
+	UnfollowUser(ctx context.Context, arg UnfollowUserParams) error
+	UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) error
+	// The current body is locked and copied into chirp_revisions in the same
+	// statement, so concurrent edits can't lose a revision.
+	UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error)
+}
+
+var _ Querier = (*Queries)(nil)
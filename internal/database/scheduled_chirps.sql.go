@@ -0,0 +1,179 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: scheduled_chirps.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countScheduledChirps = `-- name: CountScheduledChirps :one
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = $1
+`
+
+func (q *Queries) CountScheduledChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countScheduledChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countScheduledChirpsBetween = `-- name: CountScheduledChirpsBetween :one
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = $1
+AND publish_at > $2::timestamp
+AND publish_at < $3::timestamp
+`
+
+type CountScheduledChirpsBetweenParams struct {
+	UserID uuid.UUID
+	After  time.Time
+	Before time.Time
+}
+
+// Counts the user's scheduled chirps due strictly between after and before.
+func (q *Queries) CountScheduledChirpsBetween(ctx context.Context, arg CountScheduledChirpsBetweenParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countScheduledChirpsBetween, arg.UserID, arg.After, arg.Before)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createScheduledChirp = `-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, user_id, body, publish_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING id, created_at, user_id, body, publish_at
+`
+
+type CreateScheduledChirpParams struct {
+	UserID    uuid.UUID
+	Body      string
+	PublishAt time.Time
+}
+
+func (q *Queries) CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) (ScheduledChirp, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledChirp, arg.UserID, arg.Body, arg.PublishAt)
+	var i ScheduledChirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const deleteScheduledChirp = `-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteScheduledChirp(ctx context.Context, arg DeleteScheduledChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listScheduledChirps = `-- name: ListScheduledChirps :many
+SELECT id, created_at, user_id, body, publish_at FROM scheduled_chirps
+WHERE user_id = $1
+ORDER BY publish_at ASC, id ASC
+`
+
+func (q *Queries) ListScheduledChirps(ctx context.Context, userID uuid.UUID) ([]ScheduledChirp, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ScheduledChirp
+	for rows.Next() {
+		var i ScheduledChirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Body,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+WITH due AS (
+    DELETE FROM scheduled_chirps
+    WHERE publish_at <= NOW()
+    RETURNING id, created_at, user_id, body, publish_at
+)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, thread_id)
+SELECT due.id, NOW(), NOW(), due.body, due.user_id, due.id
+FROM due
+RETURNING id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at
+`
+
+// Moves every scheduled chirp whose time has come into chirps, each
+// starting its own thread. Deleting and inserting in one statement means
+// a chirp is published once even with several publishers running.
+func (q *Queries) PublishDueChirps(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.InReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
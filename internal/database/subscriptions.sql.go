@@ -0,0 +1,220 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: subscriptions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cancelSubscription = `-- name: CancelSubscription :execrows
+WITH claimed AS (
+    INSERT INTO polka_events (id, event, received_at)
+    SELECT $2::text, $3::text, NOW()
+    FROM users
+    WHERE users.id = $1
+    ON CONFLICT (id) DO NOTHING
+    RETURNING polka_events.id
+), ended AS (
+    UPDATE subscriptions
+    SET ended_at = NOW(), end_reason = 'downgraded', updated_at = NOW()
+    WHERE subscriptions.user_id = $1 AND subscriptions.ended_at IS NULL
+        AND EXISTS (SELECT 1 FROM claimed)
+)
+UPDATE users
+SET is_chirpy_red = FALSE, updated_at = NOW()
+WHERE users.id = $1 AND EXISTS (SELECT 1 FROM claimed)
+`
+
+type CancelSubscriptionParams struct {
+	UserID  uuid.UUID
+	EventID string
+	Event   string
+}
+
+// Applies a Polka downgrade: records the event, ends the user's open
+// subscription and takes away Chirpy Red, all or nothing. Affects no rows
+// when the event was applied before or the user doesn't exist.
+func (q *Queries) CancelSubscription(ctx context.Context, arg CancelSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelSubscription, arg.UserID, arg.EventID, arg.Event)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const expireSubscriptions = `-- name: ExpireSubscriptions :many
+WITH expired AS (
+    UPDATE subscriptions
+    SET ended_at = NOW(), end_reason = 'expired', updated_at = NOW()
+    WHERE subscriptions.ended_at IS NULL AND subscriptions.expires_at < $1::timestamp
+    RETURNING subscriptions.id, subscriptions.user_id, subscriptions.started_at, subscriptions.updated_at, subscriptions.expires_at, subscriptions.ended_at, subscriptions.end_reason
+), downgraded AS (
+    UPDATE users
+    SET is_chirpy_red = FALSE, updated_at = NOW()
+    WHERE users.id IN (SELECT expired.user_id FROM expired)
+)
+SELECT id, user_id, started_at, updated_at, expires_at, ended_at, end_reason FROM expired
+`
+
+type ExpireSubscriptionsRow struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	StartedAt time.Time
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+	EndedAt   sql.NullTime
+	EndReason sql.NullString
+}
+
+// Ends every open subscription that expired before the cutoff and takes
+// Chirpy Red away from its user.
+func (q *Queries) ExpireSubscriptions(ctx context.Context, cutoff time.Time) ([]ExpireSubscriptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, expireSubscriptions, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExpireSubscriptionsRow
+	for rows.Next() {
+		var i ExpireSubscriptionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.StartedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.EndedAt,
+			&i.EndReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSubscriptionsByUser = `-- name: GetSubscriptionsByUser :many
+SELECT id, user_id, started_at, updated_at, expires_at, ended_at, end_reason FROM subscriptions
+WHERE user_id = $1
+ORDER BY started_at DESC, id DESC
+`
+
+// The user's subscription history, newest first.
+func (q *Queries) GetSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
+	rows, err := q.db.QueryContext(ctx, getSubscriptionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Subscription
+	for rows.Next() {
+		var i Subscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.StartedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.EndedAt,
+			&i.EndReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renewSubscription = `-- name: RenewSubscription :one
+WITH claimed AS (
+    INSERT INTO polka_events (id, event, received_at)
+    SELECT $1::text, $2::text, NOW()
+    FROM users
+    WHERE users.id = $3
+    ON CONFLICT (id) DO NOTHING
+    RETURNING polka_events.id
+), renewed AS (
+    UPDATE subscriptions
+    SET expires_at = GREATEST(subscriptions.expires_at, $4::timestamp), updated_at = NOW()
+    WHERE subscriptions.user_id = $3 AND subscriptions.ended_at IS NULL
+        AND EXISTS (SELECT 1 FROM claimed)
+    RETURNING subscriptions.id, subscriptions.user_id, subscriptions.started_at, subscriptions.updated_at, subscriptions.expires_at, subscriptions.ended_at, subscriptions.end_reason
+), opened AS (
+    INSERT INTO subscriptions (id, user_id, started_at, updated_at, expires_at)
+    SELECT gen_random_uuid(), users.id, NOW(), NOW(), $4::timestamp
+    FROM users
+    WHERE users.id = $3
+        AND EXISTS (SELECT 1 FROM claimed) AND NOT EXISTS (SELECT 1 FROM renewed)
+    RETURNING id, user_id, started_at, updated_at, expires_at, ended_at, end_reason
+), upgraded AS (
+    UPDATE users
+    SET is_chirpy_red = TRUE, updated_at = NOW()
+    WHERE users.id = $3 AND EXISTS (SELECT 1 FROM claimed)
+)
+SELECT id, user_id, started_at, updated_at, expires_at, ended_at, end_reason FROM renewed
+UNION ALL
+SELECT id, user_id, started_at, updated_at, expires_at, ended_at, end_reason FROM opened
+`
+
+type RenewSubscriptionParams struct {
+	EventID   string
+	Event     string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+type RenewSubscriptionRow struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	StartedAt time.Time
+	UpdatedAt time.Time
+	ExpiresAt time.Time
+	EndedAt   sql.NullTime
+	EndReason sql.NullString
+}
+
+// Applies a Polka upgrade or renewal: records the event and then pushes
+// back the expiry of the user's open subscription, or opens a new one, and
+// makes the user Chirpy Red. The event is only recorded together with the
+// change, so a delivery is either applied and remembered or neither. An
+// expiry earlier than the current one is ignored, so a late redelivery
+// can't shorten a subscription. Returns no rows when the event was applied
+// before or the user doesn't exist.
+func (q *Queries) RenewSubscription(ctx context.Context, arg RenewSubscriptionParams) (RenewSubscriptionRow, error) {
+	row := q.db.QueryRowContext(ctx, renewSubscription,
+		arg.EventID,
+		arg.Event,
+		arg.UserID,
+		arg.ExpiresAt,
+	)
+	var i RenewSubscriptionRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.StartedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.EndedAt,
+		&i.EndReason,
+	)
+	return i, err
+}
@@ -117,14 +117,3 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 	)
 	return i, err
 }
-
-const upgradeToChirpyRed = `-- name: UpgradeToChirpyRed :exec
-UPDATE users
-SET is_chirpy_red = TRUE, updated_at = NOW()
-WHERE id = $1
-`
-
-func (q *Queries) UpgradeToChirpyRed(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeToChirpyRed, id)
-	return err
-}
@@ -0,0 +1,90 @@
+// Package logging sets up Chirpy's structured logs: JSON lines tagged with
+// the request ID from the context, with credentials redacted.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of any attribute that looks like a secret.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are matched against lower-cased attribute keys.
+var sensitiveKeys = []string{"authorization", "token", "password", "secret", "api_key", "apikey", "cookie"}
+
+// sensitivePrefixes catch credentials logged under an innocent key, such as
+// a whole Authorization header value.
+var sensitivePrefixes = []string{"bearer ", "apikey ", "basic "}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// NewHandler returns a JSON handler writing to w at the given level.
+// Records logged with a context carrying a request ID get a request_id
+// attribute.
+func NewHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	})}
+}
+
+// ParseLevel parses debug, info, warn or error, defaulting to info.
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+
+	if a.Value.Kind() == slog.KindString {
+		value := strings.ToLower(a.Value.String())
+		for _, prefix := range sensitivePrefixes {
+			if strings.HasPrefix(value, prefix) {
+				return slog.String(a.Key, Redacted)
+			}
+		}
+	}
+	return a
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, slog.LevelInfo))
+
+	logger.Info("login",
+		slog.String("email", "walt@breakingbad.com"),
+		slog.String("refresh_token", "abc123"),
+		slog.String("header", "Bearer eyJhbGciOi"),
+		slog.Group("req", slog.String("Authorization", "ApiKey f271c81ff7084ee5b99a5091b42d486e")),
+	)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line isn't JSON: %v", err)
+	}
+	if line["email"] != "walt@breakingbad.com" {
+		t.Errorf("expected email to be kept, got %v", line["email"])
+	}
+	for _, key := range []string{"refresh_token", "header"} {
+		if line[key] != Redacted {
+			t.Errorf("expected %s to be redacted, got %v", key, line[key])
+		}
+	}
+	if req, _ := line["req"].(map[string]any); req["Authorization"] != Redacted {
+		t.Errorf("expected grouped Authorization to be redacted, got %v", line["req"])
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, slog.LevelInfo)).With(slog.String("service", "chirpy"))
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line isn't JSON: %v", err)
+	}
+	if line["request_id"] != "req-1" {
+		t.Errorf("expected request_id req-1, got %v", line["request_id"])
+	}
+}
@@ -0,0 +1,170 @@
+// Package mailer sends Chirpy's transactional email. SMTP delivers it for
+// real; Dir and Log stand in during development so links can be followed
+// without a mail server.
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders msg as an RFC 5322 message. Header values have line
+// breaks removed so a crafted address or subject can't add headers.
+func format(from string, msg Message) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// SMTP sends through an SMTP server, authenticating with PLAIN when a
+// username is set. The connection is upgraded to TLS when the server offers
+// STARTTLS, and net/smtp refuses PLAIN auth over an unencrypted remote
+// connection.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg. It gives up when ctx is done, whether it is still
+// dialling or the server has stopped answering.
+func (m SMTP) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	if err := m.send(ctx, addr, msg); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return fmt.Errorf("sending mail to %s: %w", addr, err)
+	}
+	return nil
+}
+
+// send is smtp.SendMail over a connection bound to ctx.
+func (m SMTP) send(ctx context.Context, addr string, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("server doesn't support AUTH")
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(format(m.From, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Dir writes each message to its own file in Path, so a developer can
+// read what would have been sent.
+type Dir struct {
+	Path string
+	From string
+}
+
+func (m Dir) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Path, 0o755); err != nil {
+		return err
+	}
+	// The message is written under a temporary name and renamed, so
+	// anything watching the directory never sees half a message
+	f, err := os.CreateTemp(m.Path, time.Now().UTC().Format("20060102T150405")+"-*.eml.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(format(m.From, msg)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), strings.TrimSuffix(f.Name(), ".tmp"))
+}
+
+// Log notes messages in a logger instead of sending them. Only the
+// recipient and subject are logged: bodies carry secrets such as reset
+// links, and logs are read by more people than mailboxes. Use Dir to see
+// the bodies during development.
+type Log struct {
+	Logger *slog.Logger
+}
+
+func (m Log) Send(ctx context.Context, msg Message) error {
+	m.Logger.InfoContext(ctx, "mail not sent, no mailer configured",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+	)
+	return nil
+}
@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := Dir{Path: dir, From: "chirpy@example.com"}
+	for _, subject := range []string{"first", "second"} {
+		if err := m.Send(context.Background(), Message{To: "walt@breakingbad.com", Subject: subject, Body: "Say my name\nHeisenberg"}); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 || filepath.Ext(entries[0].Name()) != ".eml" {
+		t.Fatalf("expected a file per message, got %v, %v", entries, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"From: chirpy@example.com\r\n", "To: walt@breakingbad.com\r\n", "\r\n\r\nSay my name\r\nHeisenberg"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected the message to contain %q, got %q", want, data)
+		}
+	}
+}
+
+func TestSMTPHonoursContext(t *testing.T) {
+	// A server that accepts connections and never greets them
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	m := SMTP{Host: "127.0.0.1", Port: addr.Port, From: "chirpy@example.com"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = m.Send(ctx, Message{To: "walt@breakingbad.com", Subject: "Reset"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected Send to give up with its context, took %v", elapsed)
+	}
+}
+
+func TestFormatStripsHeaderInjection(t *testing.T) {
+	msg := string(format("chirpy@example.com", Message{
+		To:      "walt@breakingbad.com\r\nBcc: jesse@breakingbad.com",
+		Subject: "Reset\nBcc: skyler@breakingbad.com",
+	}))
+	if strings.Contains(msg, "\r\nBcc:") || strings.Contains(msg, "\nBcc:") {
+		t.Errorf("expected line breaks to be stripped from headers, got %q", msg)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	m := Log{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+	if err := m.Send(context.Background(), Message{To: "walt@breakingbad.com", Subject: "Reset", Body: "follow the link"}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	if !strings.Contains(buf.String(), "walt@breakingbad.com") || strings.Contains(buf.String(), "follow the link") {
+		t.Errorf("expected the recipient to be logged and the body left out, got %s", buf.String())
+	}
+}
@@ -0,0 +1,62 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) LikeChirp(ctx context.Context, arg database.LikeChirpParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, chirpOK := s.chirps[arg.ChirpID]
+	_, userOK := s.users[arg.UserID]
+	if !chirpOK || !userOK {
+		return ErrForeignKeyViolation
+	}
+
+	key := likeKey{arg.ChirpID, arg.UserID}
+	if _, ok := s.likes[key]; ok {
+		return nil
+	}
+	s.likes[key] = database.ChirpLike{ChirpID: arg.ChirpID, UserID: arg.UserID, CreatedAt: s.Now()}
+	chirp.LikeCount++
+	s.chirps[chirp.ID] = chirp
+	return nil
+}
+
+func (s *Store) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLike(likeKey{arg.ChirpID, arg.UserID})
+	return nil
+}
+
+func (s *Store) ListLikedChirpIDs(ctx context.Context, arg database.ListLikedChirpIDsParams) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []uuid.UUID{}
+	for _, chirpID := range arg.ChirpIds {
+		if _, ok := s.likes[likeKey{chirpID, arg.UserID}]; ok {
+			ids = append(ids, chirpID)
+		}
+	}
+	return ids, nil
+}
+
+// removeLike deletes a like and keeps like_count in step, as the
+// chirp_likes_count trigger does. Callers hold s.mu.
+func (s *Store) removeLike(key likeKey) {
+	if _, ok := s.likes[key]; !ok {
+		return
+	}
+	delete(s.likes, key)
+	if chirp, ok := s.chirps[key.ChirpID]; ok {
+		chirp.LikeCount--
+		s.chirps[key.ChirpID] = chirp
+	}
+}
@@ -0,0 +1,60 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[arg.ID]
+	if !ok || chirp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	now := s.Now()
+	writtenAt := chirp.CreatedAt
+	if chirp.EditedAt.Valid {
+		writtenAt = chirp.EditedAt.Time
+	}
+	revision := database.ChirpRevision{
+		ID:         uuid.New(),
+		ChirpID:    chirp.ID,
+		Body:       chirp.Body,
+		WrittenAt:  writtenAt,
+		ReplacedAt: now,
+	}
+	s.revisions[revision.ID] = revision
+
+	chirp.Body = arg.Body
+	chirp.UpdatedAt = now
+	chirp.EditedAt = sql.NullTime{Time: now, Valid: true}
+	s.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (s *Store) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions := []database.ChirpRevision{}
+	for _, revision := range s.revisions {
+		if revision.ChirpID == chirpID {
+			revisions = append(revisions, revision)
+		}
+	}
+	slices.SortFunc(revisions, func(a, b database.ChirpRevision) int {
+		if c := a.ReplacedAt.Compare(b.ReplacedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return revisions, nil
+}
@@ -0,0 +1,156 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return database.Chirp{}, ErrForeignKeyViolation
+	}
+	if _, ok := s.chirps[arg.InReplyToID.UUID]; arg.InReplyToID.Valid && !ok {
+		return database.Chirp{}, ErrForeignKeyViolation
+	}
+
+	now := s.Now()
+	chirp := database.Chirp{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		InReplyToID: arg.InReplyToID,
+	}
+	chirp.ThreadID = chirp.ID
+	if arg.ThreadID.Valid {
+		chirp.ThreadID = arg.ThreadID.UUID
+	}
+	s.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (s *Store) CountChirpsSince(ctx context.Context, arg database.CountChirpsSinceParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, chirp := range s.chirps {
+		if chirp.UserID == arg.UserID && arg.UserID.Valid && chirp.CreatedAt.After(arg.Since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) ListChirpsAfter(ctx context.Context, arg database.ListChirpsAfterParams) ([]database.Chirp, error) {
+	return s.listChirps(arg.AuthorID, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, false), nil
+}
+
+func (s *Store) ListChirpsBefore(ctx context.Context, arg database.ListChirpsBeforeParams) ([]database.Chirp, error) {
+	return s.listChirps(arg.AuthorID, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, true), nil
+}
+
+func (s *Store) listChirps(authorID uuid.NullUUID, cursorTime sql.NullTime, cursorID uuid.NullUUID, pageSize int32, before bool) []database.Chirp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.selectChirps(func(chirp database.Chirp) bool {
+		if chirp.DeletedAt.Valid {
+			return false
+		}
+		if authorID.Valid && (!chirp.UserID.Valid || chirp.UserID.UUID != authorID.UUID) {
+			return false
+		}
+		return afterCursor(chirp.CreatedAt, chirp.ID, cursorTime, cursorID, before)
+	}, before, pageSize)
+}
+
+func (s *Store) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok || chirp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for revisionID, revision := range s.revisions {
+		if revision.ChirpID == id {
+			delete(s.revisions, revisionID)
+		}
+	}
+	for key := range s.likes {
+		if key.ChirpID == id {
+			s.removeLike(key)
+		}
+	}
+
+	chirp, ok := s.chirps[id]
+	if !ok || chirp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	now := s.Now()
+	chirp.DeletedAt = sql.NullTime{Time: now, Valid: true}
+	chirp.UpdatedAt = now
+	chirp.Body = ""
+	s.chirps[id] = chirp
+	return chirp, nil
+}
+
+func (s *Store) GetChirpThreadID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chirp, ok := s.chirps[id]
+	if !ok {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return chirp.ThreadID, nil
+}
+
+func (s *Store) GetThread(ctx context.Context, threadID uuid.UUID) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.selectChirps(func(chirp database.Chirp) bool {
+		return chirp.ThreadID == threadID
+	}, false, -1), nil
+}
+
+// selectChirps returns the chirps matching keep ordered by (created_at,
+// id), newest first when desc is set, and cut to pageSize (-1 for no
+// limit). Callers hold s.mu.
+func (s *Store) selectChirps(keep func(database.Chirp) bool, desc bool, pageSize int32) []database.Chirp {
+	chirps := []database.Chirp{}
+	for _, chirp := range s.chirps {
+		if keep(chirp) {
+			chirps = append(chirps, chirp)
+		}
+	}
+	sortChirps(chirps, desc)
+	return limit(chirps, pageSize)
+}
+
+func sortChirps(chirps []database.Chirp, desc bool) {
+	slices.SortFunc(chirps, func(a, b database.Chirp) int {
+		c := compareKeys(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+		if desc {
+			return -c
+		}
+		return c
+	})
+}
@@ -0,0 +1,119 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) FollowUser(ctx context.Context, arg database.FollowUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.FollowerID == arg.FolloweeID {
+		return ErrCheckViolation
+	}
+	_, followerOK := s.users[arg.FollowerID]
+	_, followeeOK := s.users[arg.FolloweeID]
+	if !followerOK || !followeeOK {
+		return ErrForeignKeyViolation
+	}
+
+	key := followKey{arg.FollowerID, arg.FolloweeID}
+	if _, ok := s.follows[key]; !ok {
+		s.follows[key] = database.Follow{
+			FollowerID: arg.FollowerID,
+			FolloweeID: arg.FolloweeID,
+			CreatedAt:  s.Now(),
+		}
+	}
+	return nil
+}
+
+func (s *Store) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.follows, followKey{arg.FollowerID, arg.FolloweeID})
+	return nil
+}
+
+func (s *Store) ListFollowersBefore(ctx context.Context, arg database.ListFollowersBeforeParams) ([]database.Follow, error) {
+	return s.listFollows(arg.UserID, true, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, true), nil
+}
+
+func (s *Store) ListFollowersAfter(ctx context.Context, arg database.ListFollowersAfterParams) ([]database.Follow, error) {
+	return s.listFollows(arg.UserID, true, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, false), nil
+}
+
+func (s *Store) ListFollowingBefore(ctx context.Context, arg database.ListFollowingBeforeParams) ([]database.Follow, error) {
+	return s.listFollows(arg.UserID, false, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, true), nil
+}
+
+func (s *Store) ListFollowingAfter(ctx context.Context, arg database.ListFollowingAfterParams) ([]database.Follow, error) {
+	return s.listFollows(arg.UserID, false, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, false), nil
+}
+
+// listFollows pages through a user's followers, or the users they follow,
+// keyed on (created_at, the other user's id).
+func (s *Store) listFollows(userID uuid.UUID, followers bool, cursorTime sql.NullTime, cursorID uuid.NullUUID, pageSize int32, before bool) []database.Follow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	other := func(follow database.Follow) uuid.UUID {
+		if followers {
+			return follow.FollowerID
+		}
+		return follow.FolloweeID
+	}
+
+	follows := []database.Follow{}
+	for _, follow := range s.follows {
+		if followers && follow.FolloweeID != userID || !followers && follow.FollowerID != userID {
+			continue
+		}
+		if afterCursor(follow.CreatedAt, other(follow), cursorTime, cursorID, before) {
+			follows = append(follows, follow)
+		}
+	}
+
+	slices.SortFunc(follows, func(a, b database.Follow) int {
+		c := a.CreatedAt.Compare(b.CreatedAt)
+		if c == 0 {
+			aID, bID := other(a), other(b)
+			c = bytes.Compare(aID[:], bID[:])
+		}
+		if before {
+			return -c
+		}
+		return c
+	})
+	return limit(follows, pageSize)
+}
+
+func (s *Store) GetTimelineBefore(ctx context.Context, arg database.GetTimelineBeforeParams) ([]database.Chirp, error) {
+	return s.timeline(arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, true), nil
+}
+
+func (s *Store) GetTimelineAfter(ctx context.Context, arg database.GetTimelineAfterParams) ([]database.Chirp, error) {
+	return s.timeline(arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.PageSize, false), nil
+}
+
+func (s *Store) timeline(userID uuid.UUID, cursorTime sql.NullTime, cursorID uuid.NullUUID, pageSize int32, before bool) []database.Chirp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.selectChirps(func(chirp database.Chirp) bool {
+		if chirp.DeletedAt.Valid || !chirp.UserID.Valid {
+			return false
+		}
+		if _, ok := s.follows[followKey{userID, chirp.UserID.UUID}]; !ok {
+			return false
+		}
+		return afterCursor(chirp.CreatedAt, chirp.ID, cursorTime, cursorID, before)
+	}, before, pageSize)
+}
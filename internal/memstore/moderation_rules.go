@@ -0,0 +1,59 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) ListModerationRules(ctx context.Context) ([]database.ModerationRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := []database.ModerationRule{}
+	for _, rule := range s.moderationRules {
+		rules = append(rules, rule)
+	}
+	slices.SortFunc(rules, func(a, b database.ModerationRule) int {
+		return compareKeys(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return rules, nil
+}
+
+func (s *Store) CreateModerationRule(ctx context.Context, arg database.CreateModerationRuleParams) (database.ModerationRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.Kind != "word" && arg.Kind != "regex" || arg.Action != "mask" && arg.Action != "reject" {
+		return database.ModerationRule{}, ErrCheckViolation
+	}
+	for _, rule := range s.moderationRules {
+		if rule.Kind == arg.Kind && rule.Pattern == arg.Pattern {
+			return database.ModerationRule{}, sql.ErrNoRows
+		}
+	}
+
+	rule := database.ModerationRule{
+		ID:        uuid.New(),
+		CreatedAt: s.Now(),
+		Kind:      arg.Kind,
+		Pattern:   arg.Pattern,
+		Action:    arg.Action,
+	}
+	s.moderationRules[rule.ID] = rule
+	return rule, nil
+}
+
+func (s *Store) DeleteModerationRule(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.moderationRules[id]; !ok {
+		return 0, nil
+	}
+	delete(s.moderationRules, id)
+	return 1, nil
+}
@@ -0,0 +1,61 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := s.resetTokens[arg.TokenHash]; ok {
+		return ErrUniqueViolation
+	}
+	s.resetTokens[arg.TokenHash] = database.PasswordResetToken{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: s.Now(),
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *Store) ResetPassword(ctx context.Context, arg database.ResetPasswordParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	claimed, ok := s.resetTokens[arg.TokenHash]
+	if !ok || claimed.UsedAt.Valid || !claimed.ExpiresAt.After(now) {
+		return uuid.Nil, sql.ErrNoRows
+	}
+
+	for hash, token := range s.resetTokens {
+		if token.UserID == claimed.UserID && !token.UsedAt.Valid {
+			token.UsedAt = sql.NullTime{Time: now, Valid: true}
+			s.resetTokens[hash] = token
+		}
+	}
+
+	if user, ok := s.users[claimed.UserID]; ok {
+		user.HashedPassword = arg.HashedPassword
+		user.UpdatedAt = now
+		s.users[user.ID] = user
+	}
+
+	for hash, token := range s.refreshTokens {
+		if token.UserID.Valid && token.UserID.UUID == claimed.UserID && !token.RevokedAt.Valid {
+			token.RevokedAt = sql.NullTime{Time: now, Valid: true}
+			token.UpdatedAt = now
+			s.refreshTokens[hash] = token
+		}
+	}
+	return claimed.UserID, nil
+}
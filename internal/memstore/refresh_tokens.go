@@ -0,0 +1,101 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.refreshTokens[arg.TokenHash]; ok {
+		return database.RefreshToken{}, ErrUniqueViolation
+	}
+	if _, ok := s.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return database.RefreshToken{}, ErrForeignKeyViolation
+	}
+
+	token := s.newRefreshToken(arg.TokenHash, arg.UserID, arg.FamilyID)
+	s.refreshTokens[token.TokenHash] = token
+	return token, nil
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token, ok := s.refreshTokens[tokenHash]; ok {
+		now := s.Now()
+		token.RevokedAt = sql.NullTime{Time: now, Valid: true}
+		token.UpdatedAt = now
+		s.refreshTokens[tokenHash] = token
+	}
+	return nil
+}
+
+func (s *Store) RotateRefreshToken(ctx context.Context, arg database.RotateRefreshTokenParams) (database.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	old, ok := s.refreshTokens[arg.OldTokenHash]
+	if !ok || old.RevokedAt.Valid || !old.ExpiresAt.After(now) {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	if _, ok := s.refreshTokens[arg.NewTokenHash]; ok {
+		return database.RefreshToken{}, ErrUniqueViolation
+	}
+
+	old.RevokedAt = sql.NullTime{Time: now, Valid: true}
+	old.UpdatedAt = now
+	old.ReplacedByHash = sql.NullString{String: arg.NewTokenHash, Valid: true}
+	s.refreshTokens[old.TokenHash] = old
+
+	token := s.newRefreshToken(arg.NewTokenHash, old.UserID, old.FamilyID)
+	s.refreshTokens[token.TokenHash] = token
+	return token, nil
+}
+
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	for hash, token := range s.refreshTokens {
+		if token.FamilyID == familyID && !token.RevokedAt.Valid {
+			token.RevokedAt = sql.NullTime{Time: now, Valid: true}
+			token.UpdatedAt = now
+			s.refreshTokens[hash] = token
+		}
+	}
+	return nil
+}
+
+// newRefreshToken builds a token row that expires a month from now.
+// Callers hold s.mu.
+func (s *Store) newRefreshToken(tokenHash string, userID uuid.NullUUID, familyID uuid.UUID) database.RefreshToken {
+	now := s.Now()
+	return database.RefreshToken{
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    userID,
+		ExpiresAt: now.AddDate(0, 1, 0),
+		FamilyID:  familyID,
+	}
+}
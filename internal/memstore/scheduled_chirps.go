@@ -0,0 +1,107 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) CreateScheduledChirp(ctx context.Context, arg database.CreateScheduledChirpParams) (database.ScheduledChirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.ScheduledChirp{}, ErrForeignKeyViolation
+	}
+
+	scheduled := database.ScheduledChirp{
+		ID:        uuid.New(),
+		CreatedAt: s.Now(),
+		UserID:    arg.UserID,
+		Body:      arg.Body,
+		PublishAt: arg.PublishAt,
+	}
+	s.scheduledChirps[scheduled.ID] = scheduled
+	return scheduled, nil
+}
+
+func (s *Store) ListScheduledChirps(ctx context.Context, userID uuid.UUID) ([]database.ScheduledChirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheduled := []database.ScheduledChirp{}
+	for _, chirp := range s.scheduledChirps {
+		if chirp.UserID == userID {
+			scheduled = append(scheduled, chirp)
+		}
+	}
+	slices.SortFunc(scheduled, func(a, b database.ScheduledChirp) int {
+		return compareKeys(a.PublishAt, a.ID, b.PublishAt, b.ID)
+	})
+	return scheduled, nil
+}
+
+func (s *Store) CountScheduledChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, chirp := range s.scheduledChirps {
+		if chirp.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) CountScheduledChirpsBetween(ctx context.Context, arg database.CountScheduledChirpsBetweenParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, chirp := range s.scheduledChirps {
+		if chirp.UserID == arg.UserID && chirp.PublishAt.After(arg.After) && chirp.PublishAt.Before(arg.Before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) DeleteScheduledChirp(ctx context.Context, arg database.DeleteScheduledChirpParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if chirp, ok := s.scheduledChirps[arg.ID]; !ok || chirp.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(s.scheduledChirps, arg.ID)
+	return 1, nil
+}
+
+func (s *Store) PublishDueChirps(ctx context.Context) ([]database.Chirp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	published := []database.Chirp{}
+	for id, scheduled := range s.scheduledChirps {
+		if scheduled.PublishAt.After(now) {
+			continue
+		}
+		delete(s.scheduledChirps, id)
+
+		chirp := database.Chirp{
+			ID:        scheduled.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Body:      scheduled.Body,
+			UserID:    uuid.NullUUID{UUID: scheduled.UserID, Valid: true},
+			ThreadID:  scheduled.ID,
+		}
+		s.chirps[chirp.ID] = chirp
+		published = append(published, chirp)
+	}
+	return published, nil
+}
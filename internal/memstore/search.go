@@ -0,0 +1,195 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/NachoGz/chirpy/internal/database"
+)
+
+// SearchChirps understands the subset of to_tsquery syntax the search
+// handler produces: terms joined with &, each a word, a word:* prefix, a
+// (phrase <-> of <-> words) or any of those negated with !. Words match
+// case-insensitively but are not stemmed.
+func (s *Store) SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error) {
+	query, err := parseTSQuery(arg.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := []database.SearchChirpsRow{}
+	for _, chirp := range s.chirps {
+		if chirp.DeletedAt.Valid {
+			continue
+		}
+		if arg.AuthorID.Valid && (!chirp.UserID.Valid || chirp.UserID.UUID != arg.AuthorID.UUID) {
+			continue
+		}
+		if arg.Since.Valid && chirp.CreatedAt.Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !chirp.CreatedAt.Before(arg.Until.Time) {
+			continue
+		}
+
+		words := tokenize(chirp.Body)
+		hits, ok := query.match(words)
+		if !ok {
+			continue
+		}
+		var rank float32
+		if len(words) > 0 {
+			rank = float32(len(hits)) / float32(len(words))
+		}
+		rows = append(rows, database.SearchChirpsRow{
+			ID:          chirp.ID,
+			CreatedAt:   chirp.CreatedAt,
+			UpdatedAt:   chirp.UpdatedAt,
+			Body:        chirp.Body,
+			UserID:      chirp.UserID,
+			LikeCount:   chirp.LikeCount,
+			InReplyToID: chirp.InReplyToID,
+			ThreadID:    chirp.ThreadID,
+			DeletedAt:   chirp.DeletedAt,
+			EditedAt:    chirp.EditedAt,
+			Rank:        rank,
+			Highlight:   highlight(chirp.Body, words, hits),
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b database.SearchChirpsRow) int {
+		if a.Rank != b.Rank {
+			if a.Rank > b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return -compareKeys(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+
+	if int(arg.PageOffset) >= len(rows) {
+		return []database.SearchChirpsRow{}, nil
+	}
+	return limit(rows[arg.PageOffset:], arg.PageSize), nil
+}
+
+// word is one lexeme of a body, with its byte span for highlighting.
+type word struct {
+	text       string
+	start, end int
+}
+
+func tokenize(body string) []word {
+	var words []word
+	start := -1
+	for i, r := range body {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, word{strings.ToLower(body[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, word{strings.ToLower(body[start:]), start, len(body)})
+	}
+	return words
+}
+
+type tsTerm struct {
+	negate bool
+	prefix bool
+	// phrase holds one word, or several that must appear in a row
+	phrase []string
+}
+
+type tsQuery []tsTerm
+
+func parseTSQuery(q string) (tsQuery, error) {
+	var query tsQuery
+	for _, part := range strings.Split(q, " & ") {
+		term := tsTerm{}
+		if strings.HasPrefix(part, "!") {
+			term.negate = true
+			part = part[1:]
+		}
+		if strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")") {
+			term.phrase = strings.Split(part[1:len(part)-1], " <-> ")
+		} else {
+			if strings.HasSuffix(part, ":*") {
+				term.prefix = true
+				part = strings.TrimSuffix(part, ":*")
+			}
+			term.phrase = []string{part}
+		}
+		for i, lexeme := range term.phrase {
+			if lexeme == "" {
+				return nil, fmt.Errorf("memstore: unsupported tsquery %q", q)
+			}
+			term.phrase[i] = strings.ToLower(lexeme)
+		}
+		query = append(query, term)
+	}
+	return query, nil
+}
+
+// match reports whether words satisfy every term, and which words the
+// positive terms matched.
+func (query tsQuery) match(words []word) (map[int]bool, bool) {
+	hits := map[int]bool{}
+	for _, term := range query {
+		found := false
+		for i := range words {
+			if term.matchesAt(words, i) {
+				found = true
+				if !term.negate {
+					for j := range term.phrase {
+						hits[i+j] = true
+					}
+				}
+			}
+		}
+		if found == term.negate {
+			return nil, false
+		}
+	}
+	return hits, true
+}
+
+func (term tsTerm) matchesAt(words []word, i int) bool {
+	if i+len(term.phrase) > len(words) {
+		return false
+	}
+	for j, lexeme := range term.phrase {
+		text := words[i+j].text
+		if term.prefix && !strings.HasPrefix(text, lexeme) || !term.prefix && text != lexeme {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight HTML-escapes body and wraps the hit words in <mark> tags, like
+// the ts_headline call in SearchChirps.
+func highlight(body string, words []word, hits map[int]bool) string {
+	var b strings.Builder
+	last := 0
+	for i, w := range words {
+		if !hits[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(body[last:w.start]))
+		b.WriteString("<mark>" + html.EscapeString(body[w.start:w.end]) + "</mark>")
+		last = w.end
+	}
+	b.WriteString(html.EscapeString(body[last:]))
+	return b.String()
+}
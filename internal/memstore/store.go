@@ -0,0 +1,177 @@
+// Package memstore is an in-memory implementation of database.Querier. It
+// follows the semantics of the SQL queries closely enough to run the HTTP
+// API against in tests, without a PostgreSQL server.
+//
+// Known differences: full-text search matches whole words without
+// stemming or stop words, and ranks are not comparable with ts_rank.
+package memstore
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Errors returned where PostgreSQL would reject a write with a constraint
+// violation.
+var (
+	ErrUniqueViolation     = errors.New("memstore: unique constraint violated")
+	ErrForeignKeyViolation = errors.New("memstore: foreign key constraint violated")
+	ErrCheckViolation      = errors.New("memstore: check constraint violated")
+)
+
+// Store holds every table in maps guarded by a single mutex. It is safe
+// for concurrent use.
+type Store struct {
+	mu sync.Mutex
+
+	users           map[uuid.UUID]database.User
+	chirps          map[uuid.UUID]database.Chirp
+	likes           map[likeKey]database.ChirpLike
+	follows         map[followKey]database.Follow
+	revisions       map[uuid.UUID]database.ChirpRevision
+	refreshTokens   map[string]database.RefreshToken
+	moderationRules map[uuid.UUID]database.ModerationRule
+	polkaEvents     map[string]database.PolkaEvent
+	subscriptions   map[uuid.UUID]database.Subscription
+	scheduledChirps map[uuid.UUID]database.ScheduledChirp
+	resetTokens     map[string]database.PasswordResetToken
+
+	// Now is the clock used for NOW(). Tests may replace it before using
+	// the store.
+	Now func() time.Time
+}
+
+var _ database.Querier = (*Store)(nil)
+
+type likeKey struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type followKey struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		users:           make(map[uuid.UUID]database.User),
+		chirps:          make(map[uuid.UUID]database.Chirp),
+		likes:           make(map[likeKey]database.ChirpLike),
+		follows:         make(map[followKey]database.Follow),
+		revisions:       make(map[uuid.UUID]database.ChirpRevision),
+		refreshTokens:   make(map[string]database.RefreshToken),
+		moderationRules: make(map[uuid.UUID]database.ModerationRule),
+		polkaEvents:     make(map[string]database.PolkaEvent),
+		subscriptions:   make(map[uuid.UUID]database.Subscription),
+		scheduledChirps: make(map[uuid.UUID]database.ScheduledChirp),
+		resetTokens:     make(map[string]database.PasswordResetToken),
+		Now: func() time.Time {
+			// TIMESTAMP columns keep microseconds
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+	}
+}
+
+// compareKeys orders rows the way PostgreSQL orders (created_at, id)
+// tuples; uuids compare bytewise.
+func compareKeys(aTime time.Time, aID uuid.UUID, bTime time.Time, bID uuid.UUID) int {
+	if c := aTime.Compare(bTime); c != 0 {
+		return c
+	}
+	return bytes.Compare(aID[:], bID[:])
+}
+
+// afterCursor reports whether a row passes a keyset condition. A null
+// cursor lets every row through; otherwise the row must sort strictly after
+// the cursor (or strictly before it when before is set).
+func afterCursor(rowTime time.Time, rowID uuid.UUID, cursorTime sql.NullTime, cursorID uuid.NullUUID, before bool) bool {
+	if !cursorTime.Valid {
+		return true
+	}
+	c := compareKeys(rowTime, rowID, cursorTime.Time, cursorID.UUID)
+	if before {
+		return c < 0
+	}
+	return c > 0
+}
+
+// limit truncates rows to a LIMIT clause.
+func limit[T any](rows []T, n int32) []T {
+	if n >= 0 && int(n) < len(rows) {
+		return rows[:n]
+	}
+	return rows
+}
+
+// deleteUser removes a user and everything that references it with ON
+// DELETE CASCADE. Callers hold s.mu.
+func (s *Store) deleteUser(id uuid.UUID) {
+	delete(s.users, id)
+
+	for hash, token := range s.refreshTokens {
+		if token.UserID.Valid && token.UserID.UUID == id {
+			delete(s.refreshTokens, hash)
+		}
+	}
+	for hash, token := range s.resetTokens {
+		if token.UserID == id {
+			delete(s.resetTokens, hash)
+		}
+	}
+	for subscriptionID, subscription := range s.subscriptions {
+		if subscription.UserID == id {
+			delete(s.subscriptions, subscriptionID)
+		}
+	}
+	for scheduledID, scheduled := range s.scheduledChirps {
+		if scheduled.UserID == id {
+			delete(s.scheduledChirps, scheduledID)
+		}
+	}
+	for key := range s.follows {
+		if key.FollowerID == id || key.FolloweeID == id {
+			delete(s.follows, key)
+		}
+	}
+	for key := range s.likes {
+		if key.UserID == id {
+			s.removeLike(key)
+		}
+	}
+	for chirpID, chirp := range s.chirps {
+		if chirp.UserID.Valid && chirp.UserID.UUID == id {
+			s.deleteChirpRow(chirpID)
+		}
+	}
+}
+
+// deleteChirpRow hard-deletes a chirp, as the user cascade does. Callers
+// hold s.mu.
+func (s *Store) deleteChirpRow(id uuid.UUID) {
+	delete(s.chirps, id)
+
+	for key := range s.likes {
+		if key.ChirpID == id {
+			delete(s.likes, key)
+		}
+	}
+	for revisionID, revision := range s.revisions {
+		if revision.ChirpID == id {
+			delete(s.revisions, revisionID)
+		}
+	}
+	for replyID, reply := range s.chirps {
+		if reply.InReplyToID.Valid && reply.InReplyToID.UUID == id {
+			reply.InReplyToID = uuid.NullUUID{}
+			s.chirps[replyID] = reply
+		}
+	}
+}
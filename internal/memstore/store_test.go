@@ -0,0 +1,122 @@
+package memstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/NachoGz/chirpy/internal/storetest"
+	"github.com/google/uuid"
+)
+
+// newStore returns a store whose clock advances a second per call, so rows
+// get distinct, ordered timestamps.
+func newStore() *Store {
+	s := New()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return s
+}
+
+func mustCreateUser(t *testing.T, s *Store, email string) database.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{Email: email, HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func mustCreateChirp(t *testing.T, s *Store, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	chirp, err := s.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:   body,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create chirp: %v", err)
+	}
+	return chirp
+}
+
+func TestContract(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, now func() time.Time) database.Querier {
+		s := New()
+		s.Now = now
+		return s
+	})
+}
+
+func TestLikesCascade(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+	author := mustCreateUser(t, s, "walt@breakingbad.com")
+	fan := mustCreateUser(t, s, "jesse@breakingbad.com")
+	chirp := mustCreateChirp(t, s, author.ID, "Say my name")
+
+	for i := 0; i < 2; i++ {
+		if err := s.LikeChirp(ctx, database.LikeChirpParams{ChirpID: chirp.ID, UserID: fan.ID}); err != nil {
+			t.Fatalf("failed to like: %v", err)
+		}
+	}
+	if got, _ := s.GetChirpByID(ctx, chirp.ID); got.LikeCount != 1 {
+		t.Errorf("expected likes to be idempotent, got count %d", got.LikeCount)
+	}
+
+	if err := s.FollowUser(ctx, database.FollowUserParams{FollowerID: fan.ID, FolloweeID: author.ID}); err != nil {
+		t.Fatalf("failed to follow: %v", err)
+	}
+	timeline, _ := s.GetTimelineBefore(ctx, database.GetTimelineBeforeParams{UserID: fan.ID, PageSize: 10})
+	if len(timeline) != 1 || timeline[0].ID != chirp.ID {
+		t.Errorf("unexpected timeline %+v", timeline)
+	}
+
+	s.mu.Lock()
+	s.deleteUser(fan.ID)
+	s.mu.Unlock()
+	if got, _ := s.GetChirpByID(ctx, chirp.ID); got.LikeCount != 0 {
+		t.Errorf("expected the cascade to remove the like, got count %d", got.LikeCount)
+	}
+	followers, _ := s.ListFollowersBefore(ctx, database.ListFollowersBeforeParams{UserID: author.ID, PageSize: 10})
+	if len(followers) != 0 {
+		t.Errorf("expected the cascade to remove the follow, got %+v", followers)
+	}
+}
+
+func TestSearchChirps(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+	user := mustCreateUser(t, s, "walt@breakingbad.com")
+	mustCreateChirp(t, s, user.ID, "I am the one who knocks")
+	mustCreateChirp(t, s, user.ID, "Knock knock, who is there?")
+	mustCreateChirp(t, s, user.ID, "Say my name")
+
+	cases := map[string]int{
+		"knocks":                   1,
+		"knock:*":                  2,
+		"(who <-> knocks)":         1,
+		"knock:* & !there":         1,
+		"(knocks <-> who)":         0,
+		"name & say":               1,
+		"!name & !knock:*":         0,
+		"(one <-> who <-> knocks)": 1,
+	}
+	for query, expected := range cases {
+		rows, err := s.SearchChirps(ctx, database.SearchChirpsParams{Query: query, PageSize: 10})
+		if err != nil {
+			t.Fatalf("%q: %v", query, err)
+		}
+		if len(rows) != expected {
+			t.Errorf("%q: expected %d results, got %d", query, expected, len(rows))
+		}
+	}
+
+	rows, _ := s.SearchChirps(ctx, database.SearchChirpsParams{Query: "name", PageSize: 10})
+	if rows[0].Highlight != "Say my <mark>name</mark>" {
+		t.Errorf("unexpected highlight %q", rows[0].Highlight)
+	}
+}
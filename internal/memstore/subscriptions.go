@@ -0,0 +1,133 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (s *Store) RenewSubscription(ctx context.Context, arg database.RenewSubscriptionParams) (database.RenewSubscriptionRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.UserID]
+	if !ok || !s.claimPolkaEvent(arg.EventID, arg.Event) {
+		return database.RenewSubscriptionRow{}, sql.ErrNoRows
+	}
+
+	now := s.Now()
+	subscription, ok := s.openSubscription(arg.UserID)
+	if ok {
+		if arg.ExpiresAt.After(subscription.ExpiresAt) {
+			subscription.ExpiresAt = arg.ExpiresAt
+		}
+		subscription.UpdatedAt = now
+	} else {
+		subscription = database.Subscription{
+			ID:        uuid.New(),
+			UserID:    arg.UserID,
+			StartedAt: now,
+			UpdatedAt: now,
+			ExpiresAt: arg.ExpiresAt,
+		}
+	}
+	s.subscriptions[subscription.ID] = subscription
+
+	user.IsChirpyRed = true
+	user.UpdatedAt = now
+	s.users[user.ID] = user
+	return database.RenewSubscriptionRow(subscription), nil
+}
+
+func (s *Store) CancelSubscription(ctx context.Context, arg database.CancelSubscriptionParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.UserID]
+	if !ok || !s.claimPolkaEvent(arg.EventID, arg.Event) {
+		return 0, nil
+	}
+
+	now := s.Now()
+	if subscription, ok := s.openSubscription(arg.UserID); ok {
+		s.endSubscription(subscription, "downgraded", now)
+	}
+	user.IsChirpyRed = false
+	user.UpdatedAt = now
+	s.users[user.ID] = user
+	return 1, nil
+}
+
+func (s *Store) ExpireSubscriptions(ctx context.Context, cutoff time.Time) ([]database.ExpireSubscriptionsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	var expired []database.ExpireSubscriptionsRow
+	for _, subscription := range s.subscriptions {
+		if subscription.EndedAt.Valid || !subscription.ExpiresAt.Before(cutoff) {
+			continue
+		}
+		expired = append(expired, database.ExpireSubscriptionsRow(s.endSubscription(subscription, "expired", now)))
+
+		user := s.users[subscription.UserID]
+		user.IsChirpyRed = false
+		user.UpdatedAt = now
+		s.users[user.ID] = user
+	}
+	return expired, nil
+}
+
+func (s *Store) GetSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]database.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriptions := []database.Subscription{}
+	for _, subscription := range s.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	slices.SortFunc(subscriptions, func(a, b database.Subscription) int {
+		return compareKeys(b.StartedAt, b.ID, a.StartedAt, a.ID)
+	})
+	return subscriptions, nil
+}
+
+// claimPolkaEvent records the event and reports whether it is new. Callers
+// hold s.mu and apply the event before releasing it.
+func (s *Store) claimPolkaEvent(id, event string) bool {
+	if _, ok := s.polkaEvents[id]; ok {
+		return false
+	}
+	s.polkaEvents[id] = database.PolkaEvent{
+		ID:         id,
+		Event:      event,
+		ReceivedAt: s.Now(),
+	}
+	return true
+}
+
+// openSubscription finds the subscription of userID that hasn't ended.
+// Callers hold s.mu.
+func (s *Store) openSubscription(userID uuid.UUID) (database.Subscription, bool) {
+	for _, subscription := range s.subscriptions {
+		if subscription.UserID == userID && !subscription.EndedAt.Valid {
+			return subscription, true
+		}
+	}
+	return database.Subscription{}, false
+}
+
+// endSubscription closes subscription for reason. Callers hold s.mu.
+func (s *Store) endSubscription(subscription database.Subscription, reason string, now time.Time) database.Subscription {
+	subscription.EndedAt = sql.NullTime{Time: now, Valid: true}
+	subscription.EndReason = sql.NullString{String: reason, Valid: true}
+	subscription.UpdatedAt = now
+	s.subscriptions[subscription.ID] = subscription
+	return subscription
+}
@@ -0,0 +1,294 @@
+// Package moderation checks chirp bodies against a chain of filters built
+// from word lists and regular expressions. Each rule either masks what it
+// matches or rejects the chirp outright. Rules can come from several
+// sources and be reloaded while the server is running.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Mask is what matched words and patterns are replaced with.
+const Mask = "****"
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionReject Action = "reject"
+)
+
+type Kind string
+
+const (
+	KindWord  Kind = "word"
+	KindRegex Kind = "regex"
+)
+
+// Rule is a single moderation rule as stored in a source.
+type Rule struct {
+	Kind    Kind
+	Pattern string
+	Action  Action
+}
+
+// Validate reports whether the rule could be compiled into a filter.
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionMask, ActionReject:
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+
+	switch r.Kind {
+	case KindWord:
+		// Bodies are matched a word at a time, so a word rule with a space
+		// in it could never match
+		if strings.ContainsFunc(r.Pattern, unicode.IsSpace) {
+			return errors.New("word rules match a single word; use a regex rule for phrases")
+		}
+		if normalizeWord(r.Pattern) == "" {
+			return errors.New("word rules need at least one letter or digit")
+		}
+	case KindRegex:
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	return nil
+}
+
+// Result is the outcome of moderating a body.
+type Result struct {
+	// Body is the body with every masked match replaced.
+	Body string
+	// Rejected is set when a reject rule matched; Body is then the
+	// original text.
+	Rejected bool
+	// Matches lists the rules that matched, for logging.
+	Matches []string
+}
+
+// Filter is one step of the moderation chain.
+type Filter interface {
+	// Apply checks body and returns it with any masking applied, whether it
+	// must be rejected and a description of each match.
+	Apply(body string) (masked string, rejected bool, matches []string)
+}
+
+// Chain runs filters in order. A rejecting filter stops the chain.
+type Chain []Filter
+
+func (c Chain) Moderate(body string) Result {
+	result := Result{Body: body}
+	for _, filter := range c {
+		masked, rejected, matches := filter.Apply(result.Body)
+		result.Matches = append(result.Matches, matches...)
+		if rejected {
+			result.Body = body
+			result.Rejected = true
+			return result
+		}
+		result.Body = masked
+	}
+	return result
+}
+
+// Compile builds a chain from rules. Reject rules run before mask rules so
+// they always see the text as written.
+func Compile(rules []Rule) (Chain, error) {
+	words := map[Action][]string{}
+	patterns := map[Action][]*regexp.Regexp{}
+
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("%s rule %q: %w", rule.Kind, rule.Pattern, err)
+		}
+		switch rule.Kind {
+		case KindWord:
+			words[rule.Action] = append(words[rule.Action], rule.Pattern)
+		case KindRegex:
+			patterns[rule.Action] = append(patterns[rule.Action], regexp.MustCompile("(?i)"+rule.Pattern))
+		}
+	}
+
+	var chain Chain
+	for _, action := range []Action{ActionReject, ActionMask} {
+		if len(words[action]) > 0 {
+			chain = append(chain, NewWordFilter(words[action], action))
+		}
+		for _, re := range patterns[action] {
+			chain = append(chain, &RegexFilter{re: re, action: action})
+		}
+	}
+	return chain, nil
+}
+
+// WordFilter matches whole words regardless of case, accents, compatibility
+// forms (e.g. full-width letters) and surrounding punctuation, so
+// "Kerfuffle!" and "ＫＥＲＦＵＦＦＬＥ" both match "kerfuffle".
+type WordFilter struct {
+	words  map[string]struct{}
+	action Action
+}
+
+func NewWordFilter(words []string, action Action) *WordFilter {
+	f := &WordFilter{words: make(map[string]struct{}, len(words)), action: action}
+	for _, word := range words {
+		if normalized := normalizeWord(word); normalized != "" {
+			f.words[normalized] = struct{}{}
+		}
+	}
+	return f
+}
+
+func (f *WordFilter) Apply(body string) (string, bool, []string) {
+	var b strings.Builder
+	var matches []string
+
+	rest := body
+	for len(rest) > 0 {
+		start := strings.IndexFunc(rest, isWordRune)
+		if start < 0 {
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexFunc(rest[start:], func(r rune) bool { return !isWordRune(r) })
+		if end < 0 {
+			end = len(rest)
+		} else {
+			end += start
+		}
+
+		b.WriteString(rest[:start])
+		word := rest[start:end]
+		if _, ok := f.words[normalizeWord(word)]; ok {
+			matches = append(matches, "word:"+word)
+			if f.action == ActionReject {
+				return body, true, matches
+			}
+			b.WriteString(Mask)
+		} else {
+			b.WriteString(word)
+		}
+		rest = rest[end:]
+	}
+
+	return b.String(), false, matches
+}
+
+// RegexFilter matches a case-insensitive regular expression anywhere in the
+// body.
+type RegexFilter struct {
+	re     *regexp.Regexp
+	action Action
+}
+
+func (f *RegexFilter) Apply(body string) (string, bool, []string) {
+	if !f.re.MatchString(body) {
+		return body, false, nil
+	}
+	matches := []string{"regex:" + f.re.String()}
+	if f.action == ActionReject {
+		return body, true, matches
+	}
+	return f.re.ReplaceAllLiteralString(body, Mask), false, matches
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// normalizeWord folds a word to the form word lists are compared in:
+// compatibility-decomposed, without combining marks, lower case and with
+// anything that isn't a letter or digit removed.
+func normalizeWord(word string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)))
+	folded, _, err := transform.String(t, word)
+	if err != nil {
+		folded = word
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, folded)
+}
+
+// Source supplies rules, e.g. from a file or the database.
+type Source interface {
+	Rules(ctx context.Context) ([]Rule, error)
+}
+
+// Moderator holds the current chain and rebuilds it from its sources on
+// Reload. It's safe for concurrent use; chirps being moderated during a
+// reload see either the old or the new chain.
+type Moderator struct {
+	sources []Source
+	chain   atomic.Pointer[Chain]
+}
+
+// New returns a moderator with an empty chain; call Reload to load rules.
+func New(sources ...Source) *Moderator {
+	m := &Moderator{sources: sources}
+	m.chain.Store(&Chain{})
+	return m
+}
+
+// Moderate runs body through the current chain.
+func (m *Moderator) Moderate(body string) Result {
+	return m.chain.Load().Moderate(body)
+}
+
+// Reload rebuilds the chain from every source. On error the current chain
+// is kept.
+func (m *Moderator) Reload(ctx context.Context) error {
+	var rules []Rule
+	for _, source := range m.sources {
+		sourceRules, err := source.Rules(ctx)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, sourceRules...)
+	}
+
+	chain, err := Compile(rules)
+	if err != nil {
+		return err
+	}
+	m.chain.Store(&chain)
+	return nil
+}
+
+// Watch reloads the rules every interval until ctx is done, so rule changes
+// made through another replica are picked up.
+func (m *Moderator) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(ctx); err != nil {
+				slog.ErrorContext(ctx, "Couldn't reload moderation rules", slog.Any("error", err))
+			}
+		}
+	}
+}
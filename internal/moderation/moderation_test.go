@@ -0,0 +1,124 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWordFilterMasks(t *testing.T) {
+	chain, err := Compile(WordList(DefaultWords))
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+
+	cases := map[string]string{
+		"This is a kerfuffle opinion": "This is a **** opinion",
+		"What a Kerfuffle!":           "What a ****!",
+		"sharbert, fornax.":           "****, ****.",
+		"KÉRFUFFLE again":             "**** again",
+		"ｋｅｒｆｕｆｆｌｅ":                   "****",
+		"kerfuffles are fine":         "kerfuffles are fine",
+		"nothing to see here":         "nothing to see here",
+	}
+	for body, expected := range cases {
+		result := chain.Moderate(body)
+		if result.Rejected {
+			t.Errorf("%q: unexpected rejection", body)
+		}
+		if result.Body != expected {
+			t.Errorf("%q: expected %q, got %q", body, expected, result.Body)
+		}
+	}
+}
+
+func TestRejectRules(t *testing.T) {
+	chain, err := Compile([]Rule{
+		{Kind: KindWord, Pattern: "kerfuffle", Action: ActionMask},
+		{Kind: KindWord, Pattern: "forbidden", Action: ActionReject},
+		{Kind: KindRegex, Pattern: `buy\s+now`, Action: ActionReject},
+	})
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+
+	for _, body := range []string{"kerfuffle is Forbidden!", "BUY   NOW while stocks last"} {
+		result := chain.Moderate(body)
+		if !result.Rejected {
+			t.Errorf("%q: expected rejection", body)
+		}
+		if result.Body != body {
+			t.Errorf("%q: rejected body should be left as written, got %q", body, result.Body)
+		}
+	}
+
+	if result := chain.Moderate("a kerfuffle"); result.Rejected || result.Body != "a ****" {
+		t.Errorf("expected masking only, got %+v", result)
+	}
+}
+
+func TestRegexMask(t *testing.T) {
+	chain, err := Compile([]Rule{{Kind: KindRegex, Pattern: `fo+bar`, Action: ActionMask}})
+	if err != nil {
+		t.Fatalf("failed to compile rules: %v", err)
+	}
+
+	if got := chain.Moderate("say FOOOBAR twice: foobar").Body; got != "say **** twice: ****" {
+		t.Errorf("unexpected masked body %q", got)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, rule := range []Rule{
+		{Kind: KindRegex, Pattern: "(", Action: ActionMask},
+		{Kind: KindWord, Pattern: "!!!", Action: ActionMask},
+		{Kind: KindWord, Pattern: "say my name", Action: ActionMask},
+		{Kind: KindWord, Pattern: "tab\tword", Action: ActionMask},
+		{Kind: KindWord, Pattern: "word", Action: "delete"},
+		{Kind: "phrase", Pattern: "word", Action: ActionMask},
+	} {
+		if _, err := Compile([]Rule{rule}); err == nil {
+			t.Errorf("expected error compiling %+v", rule)
+		}
+	}
+}
+
+func TestFileSourceReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	if err := os.WriteFile(path, []byte("# comment\nkerfuffle\n!forbidden\n/fo+bar/\n"), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+
+	m := New(FileSource(path))
+	if err := m.Reload(context.Background()); err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	if got := m.Moderate("kerfuffle and fooobar").Body; got != "**** and ****" {
+		t.Errorf("unexpected masked body %q", got)
+	}
+	if !m.Moderate("forbidden").Rejected {
+		t.Error("expected rejection")
+	}
+
+	// A broken file keeps the previous rules in place
+	if err := os.WriteFile(path, []byte("/(/\n"), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	if err := m.Reload(context.Background()); err == nil {
+		t.Fatal("expected error reloading an invalid regex")
+	}
+	if got := m.Moderate("kerfuffle").Body; got != "****" {
+		t.Errorf("expected old rules to still apply, got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("sharbert\n"), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	if err := m.Reload(context.Background()); err != nil {
+		t.Fatalf("failed to reload rules: %v", err)
+	}
+	if got := m.Moderate("kerfuffle sharbert").Body; got != "kerfuffle ****" {
+		t.Errorf("expected the new rules only, got %q", got)
+	}
+}
@@ -0,0 +1,77 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultWords are masked when no word list file is configured.
+var DefaultWords = []string{"kerfuffle", "sharbert", "fornax"}
+
+// StaticSource serves a fixed set of rules.
+type StaticSource []Rule
+
+func (s StaticSource) Rules(ctx context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+// WordList masks every word in words.
+func WordList(words []string) StaticSource {
+	rules := make(StaticSource, 0, len(words))
+	for _, word := range words {
+		rules = append(rules, Rule{Kind: KindWord, Pattern: word, Action: ActionMask})
+	}
+	return rules
+}
+
+// FileSource reads rules from a text file, one per line and re-read on
+// every reload:
+//
+//	kerfuffle      masks the word
+//	!slur          rejects chirps containing the word
+//	/fo+bar/       masks matches of the regular expression
+//	!/fo+bar/      rejects chirps matching the regular expression
+//
+// Blank lines and lines starting with # are ignored.
+type FileSource string
+
+func (path FileSource) Rules(ctx context.Context) ([]Rule, error) {
+	f, err := os.Open(string(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := Rule{Kind: KindWord, Action: ActionMask}
+		if strings.HasPrefix(line, "!") {
+			rule.Action = ActionReject
+			line = line[1:]
+		}
+		if len(line) > 1 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+			rule.Kind = KindRegex
+			line = line[1 : len(line)-1]
+		}
+		rule.Pattern = line
+
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,45 @@
+package sqlitestore
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const likeChirp = `
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (chirp_id, user_id) DO NOTHING`
+
+func (s *Store) LikeChirp(ctx context.Context, arg database.LikeChirpParams) error {
+	_, err := s.db.ExecContext(ctx, likeChirp, arg.ChirpID, arg.UserID, s.now())
+	return err
+}
+
+const unlikeChirp = `DELETE FROM chirp_likes WHERE chirp_id = ?1 AND user_id = ?2`
+
+func (s *Store) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	_, err := s.db.ExecContext(ctx, unlikeChirp, arg.ChirpID, arg.UserID)
+	return err
+}
+
+// SQLite has no arrays, so the chirp ids are passed as a JSON array.
+const listLikedChirpIDs = `
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = ?1
+AND chirp_id IN (SELECT value FROM json_each(?2))`
+
+func (s *Store) ListLikedChirpIDs(ctx context.Context, arg database.ListLikedChirpIDsParams) ([]uuid.UUID, error) {
+	chirpIDs, err := json.Marshal(arg.ChirpIds)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, listLikedChirpIDs, arg.UserID, string(chirpIDs))
+	return scanAll(rows, err, func(row scanner) (uuid.UUID, error) {
+		var chirpID uuid.UUID
+		err := row.Scan(&chirpID)
+		return chirpID, err
+	})
+}
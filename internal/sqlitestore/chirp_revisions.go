@@ -0,0 +1,54 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const revisionColumns = "id, chirp_id, body, written_at, replaced_at"
+
+const saveRevision = `
+INSERT INTO chirp_revisions (id, chirp_id, body, written_at, replaced_at)
+SELECT ?2, id, body, COALESCE(edited_at, created_at), ?3
+FROM chirps
+WHERE id = ?1 AND deleted_at IS NULL`
+
+const updateChirpBody = `
+UPDATE chirps
+SET body = ?2, updated_at = ?3, edited_at = ?3
+WHERE id = ?1 AND deleted_at IS NULL
+RETURNING ` + chirpColumns
+
+// UpdateChirpBody copies the current body into chirp_revisions and
+// replaces it in one write transaction, so concurrent edits can't lose a
+// revision.
+func (s *Store) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error) {
+	now := s.now()
+	var chirp database.Chirp
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, saveRevision, arg.ID, uuid.New(), now); err != nil {
+			return err
+		}
+		var err error
+		chirp, err = scanChirp(tx.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body, now))
+		return err
+	})
+	return chirp, err
+}
+
+const listChirpRevisions = `
+SELECT ` + revisionColumns + ` FROM chirp_revisions
+WHERE chirp_id = ?1
+ORDER BY replaced_at ASC, id ASC`
+
+func (s *Store) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error) {
+	rows, err := s.db.QueryContext(ctx, listChirpRevisions, chirpID)
+	return scanAll(rows, err, func(row scanner) (database.ChirpRevision, error) {
+		var i database.ChirpRevision
+		err := row.Scan(&i.ID, &i.ChirpID, &i.Body, &i.WrittenAt, &i.ReplacedAt)
+		return i, err
+	})
+}
@@ -0,0 +1,148 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const chirpColumns = "id, created_at, updated_at, body, user_id, like_count, in_reply_to_id, thread_id, deleted_at, edited_at"
+
+// chirpColumnsOf is chirpColumns qualified with the chirps table, for
+// joins.
+const chirpColumnsOf = "chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.like_count, chirps.in_reply_to_id, chirps.thread_id, chirps.deleted_at, chirps.edited_at"
+
+func scanChirp(row scanner) (database.Chirp, error) {
+	var i database.Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.InReplyToID,
+		&i.ThreadID,
+		&i.DeletedAt,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+// Root chirps start their own thread, so their id doubles as thread_id.
+const createChirp = `
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, thread_id)
+VALUES (?1, ?2, ?2, ?3, ?4, ?5, COALESCE(?6, ?1))
+RETURNING ` + chirpColumns
+
+func (s *Store) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	return scanChirp(s.db.QueryRowContext(ctx, createChirp,
+		uuid.New(),
+		s.now(),
+		arg.Body,
+		arg.UserID,
+		arg.InReplyToID,
+		arg.ThreadID,
+	))
+}
+
+const countChirpsSince = `
+SELECT COUNT(*) FROM chirps
+WHERE user_id = ?1 AND created_at > ?2`
+
+func (s *Store) CountChirpsSince(ctx context.Context, arg database.CountChirpsSinceParams) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, countChirpsSince, arg.UserID, timestamp(arg.Since)).Scan(&n)
+	return n, err
+}
+
+const listChirpsAfter = `
+SELECT ` + chirpColumns + ` FROM chirps
+WHERE deleted_at IS NULL
+AND (?1 IS NULL OR user_id = ?1)
+AND (?2 IS NULL OR (created_at, id) > (?2, ?3))
+ORDER BY created_at ASC, id ASC
+LIMIT ?4`
+
+func (s *Store) ListChirpsAfter(ctx context.Context, arg database.ListChirpsAfterParams) ([]database.Chirp, error) {
+	rows, err := s.db.QueryContext(ctx, listChirpsAfter,
+		arg.AuthorID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanChirp)
+}
+
+const listChirpsBefore = `
+SELECT ` + chirpColumns + ` FROM chirps
+WHERE deleted_at IS NULL
+AND (?1 IS NULL OR user_id = ?1)
+AND (?2 IS NULL OR (created_at, id) < (?2, ?3))
+ORDER BY created_at DESC, id DESC
+LIMIT ?4`
+
+func (s *Store) ListChirpsBefore(ctx context.Context, arg database.ListChirpsBeforeParams) ([]database.Chirp, error) {
+	rows, err := s.db.QueryContext(ctx, listChirpsBefore,
+		arg.AuthorID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanChirp)
+}
+
+const getChirpByID = `SELECT ` + chirpColumns + ` FROM chirps WHERE id = ?1 AND deleted_at IS NULL`
+
+func (s *Store) GetChirpByID(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	return scanChirp(s.db.QueryRowContext(ctx, getChirpByID, id))
+}
+
+const deleteChirpRevisions = `DELETE FROM chirp_revisions WHERE chirp_id = ?1`
+
+const deleteChirpLikes = `DELETE FROM chirp_likes WHERE chirp_id = ?1`
+
+const deleteChirp = `
+UPDATE chirps
+SET deleted_at = ?2, updated_at = ?2, body = ''
+WHERE id = ?1 AND deleted_at IS NULL
+RETURNING ` + chirpColumns
+
+// DeleteChirp leaves a tombstone. Old bodies and likes go with the chirp;
+// the tombstone keeps nothing the author wrote and no one's reaction to
+// it. Deleting the likes first lets the trigger zero like_count.
+func (s *Store) DeleteChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	var chirp database.Chirp
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteChirpRevisions, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, deleteChirpLikes, id); err != nil {
+			return err
+		}
+		var err error
+		chirp, err = scanChirp(tx.QueryRowContext(ctx, deleteChirp, id, s.now()))
+		return err
+	})
+	return chirp, err
+}
+
+const getChirpThreadID = `SELECT thread_id FROM chirps WHERE id = ?1`
+
+func (s *Store) GetChirpThreadID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	var threadID uuid.UUID
+	err := s.db.QueryRowContext(ctx, getChirpThreadID, id).Scan(&threadID)
+	return threadID, err
+}
+
+const getThread = `
+SELECT ` + chirpColumns + ` FROM chirps
+WHERE thread_id = ?1
+ORDER BY created_at ASC, id ASC`
+
+func (s *Store) GetThread(ctx context.Context, threadID uuid.UUID) ([]database.Chirp, error) {
+	rows, err := s.db.QueryContext(ctx, getThread, threadID)
+	return scanAll(rows, err, scanChirp)
+}
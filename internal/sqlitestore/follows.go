@@ -0,0 +1,138 @@
+package sqlitestore
+
+import (
+	"context"
+
+	"github.com/NachoGz/chirpy/internal/database"
+)
+
+const followColumns = "follower_id, followee_id, created_at"
+
+func scanFollow(row scanner) (database.Follow, error) {
+	var i database.Follow
+	err := row.Scan(&i.FollowerID, &i.FolloweeID, &i.CreatedAt)
+	return i, err
+}
+
+const followUser = `
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (follower_id, followee_id) DO NOTHING`
+
+func (s *Store) FollowUser(ctx context.Context, arg database.FollowUserParams) error {
+	_, err := s.db.ExecContext(ctx, followUser, arg.FollowerID, arg.FolloweeID, s.now())
+	return err
+}
+
+const unfollowUser = `DELETE FROM follows WHERE follower_id = ?1 AND followee_id = ?2`
+
+func (s *Store) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	_, err := s.db.ExecContext(ctx, unfollowUser, arg.FollowerID, arg.FolloweeID)
+	return err
+}
+
+const listFollowersBefore = `
+SELECT ` + followColumns + ` FROM follows
+WHERE followee_id = ?1
+AND (?2 IS NULL OR (created_at, follower_id) < (?2, ?3))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT ?4`
+
+func (s *Store) ListFollowersBefore(ctx context.Context, arg database.ListFollowersBeforeParams) ([]database.Follow, error) {
+	rows, err := s.db.QueryContext(ctx, listFollowersBefore,
+		arg.UserID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanFollow)
+}
+
+const listFollowersAfter = `
+SELECT ` + followColumns + ` FROM follows
+WHERE followee_id = ?1
+AND (?2 IS NULL OR (created_at, follower_id) > (?2, ?3))
+ORDER BY created_at ASC, follower_id ASC
+LIMIT ?4`
+
+func (s *Store) ListFollowersAfter(ctx context.Context, arg database.ListFollowersAfterParams) ([]database.Follow, error) {
+	rows, err := s.db.QueryContext(ctx, listFollowersAfter,
+		arg.UserID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanFollow)
+}
+
+const listFollowingBefore = `
+SELECT ` + followColumns + ` FROM follows
+WHERE follower_id = ?1
+AND (?2 IS NULL OR (created_at, followee_id) < (?2, ?3))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT ?4`
+
+func (s *Store) ListFollowingBefore(ctx context.Context, arg database.ListFollowingBeforeParams) ([]database.Follow, error) {
+	rows, err := s.db.QueryContext(ctx, listFollowingBefore,
+		arg.UserID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanFollow)
+}
+
+const listFollowingAfter = `
+SELECT ` + followColumns + ` FROM follows
+WHERE follower_id = ?1
+AND (?2 IS NULL OR (created_at, followee_id) > (?2, ?3))
+ORDER BY created_at ASC, followee_id ASC
+LIMIT ?4`
+
+func (s *Store) ListFollowingAfter(ctx context.Context, arg database.ListFollowingAfterParams) ([]database.Follow, error) {
+	rows, err := s.db.QueryContext(ctx, listFollowingAfter,
+		arg.UserID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanFollow)
+}
+
+const getTimelineBefore = `
+SELECT ` + chirpColumnsOf + ` FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = ?1
+AND chirps.deleted_at IS NULL
+AND (?2 IS NULL OR (chirps.created_at, chirps.id) < (?2, ?3))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT ?4`
+
+func (s *Store) GetTimelineBefore(ctx context.Context, arg database.GetTimelineBeforeParams) ([]database.Chirp, error) {
+	rows, err := s.db.QueryContext(ctx, getTimelineBefore,
+		arg.UserID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanChirp)
+}
+
+const getTimelineAfter = `
+SELECT ` + chirpColumnsOf + ` FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = ?1
+AND chirps.deleted_at IS NULL
+AND (?2 IS NULL OR (chirps.created_at, chirps.id) > (?2, ?3))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT ?4`
+
+func (s *Store) GetTimelineAfter(ctx context.Context, arg database.GetTimelineAfterParams) ([]database.Chirp, error) {
+	rows, err := s.db.QueryContext(ctx, getTimelineAfter,
+		arg.UserID,
+		nullTimestamp(arg.CursorCreatedAt),
+		arg.CursorID,
+		arg.PageSize,
+	)
+	return scanAll(rows, err, scanChirp)
+}
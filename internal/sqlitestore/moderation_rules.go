@@ -0,0 +1,51 @@
+package sqlitestore
+
+import (
+	"context"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const moderationRuleColumns = "id, created_at, kind, pattern, action"
+
+func scanModerationRule(row scanner) (database.ModerationRule, error) {
+	var i database.ModerationRule
+	err := row.Scan(&i.ID, &i.CreatedAt, &i.Kind, &i.Pattern, &i.Action)
+	return i, err
+}
+
+const listModerationRules = `
+SELECT ` + moderationRuleColumns + ` FROM moderation_rules
+ORDER BY created_at ASC, id ASC`
+
+func (s *Store) ListModerationRules(ctx context.Context) ([]database.ModerationRule, error) {
+	rows, err := s.db.QueryContext(ctx, listModerationRules)
+	return scanAll(rows, err, scanModerationRule)
+}
+
+const createModerationRule = `
+INSERT INTO moderation_rules (id, created_at, kind, pattern, action)
+VALUES (?1, ?2, ?3, ?4, ?5)
+ON CONFLICT (kind, pattern) DO NOTHING
+RETURNING ` + moderationRuleColumns
+
+func (s *Store) CreateModerationRule(ctx context.Context, arg database.CreateModerationRuleParams) (database.ModerationRule, error) {
+	return scanModerationRule(s.db.QueryRowContext(ctx, createModerationRule,
+		uuid.New(),
+		s.now(),
+		arg.Kind,
+		arg.Pattern,
+		arg.Action,
+	))
+}
+
+const deleteModerationRule = `DELETE FROM moderation_rules WHERE id = ?1`
+
+func (s *Store) DeleteModerationRule(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deleteModerationRule, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
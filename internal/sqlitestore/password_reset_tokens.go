@@ -0,0 +1,65 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const createPasswordResetToken = `
+INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at)
+VALUES (?1, ?2, ?3, ?4)`
+
+func (s *Store) CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error {
+	_, err := s.db.ExecContext(ctx, createPasswordResetToken,
+		arg.TokenHash,
+		arg.UserID,
+		s.now(),
+		timestamp(arg.ExpiresAt),
+	)
+	return err
+}
+
+const claimPasswordResetToken = `
+UPDATE password_reset_tokens
+SET used_at = ?2
+WHERE token_hash = ?1 AND used_at IS NULL AND expires_at > ?2
+RETURNING user_id`
+
+const usePasswordResetTokens = `
+UPDATE password_reset_tokens
+SET used_at = ?2
+WHERE user_id = ?1 AND used_at IS NULL`
+
+const setPassword = `
+UPDATE users
+SET hashed_password = ?2, updated_at = ?3
+WHERE id = ?1`
+
+const revokeUserRefreshTokens = `
+UPDATE refresh_tokens
+SET revoked_at = ?2, updated_at = ?2
+WHERE user_id = ?1 AND revoked_at IS NULL`
+
+// ResetPassword claims the token and applies the reset in one write
+// transaction, which SQLite serialises, so a token can only be used once.
+func (s *Store) ResetPassword(ctx context.Context, arg database.ResetPasswordParams) (uuid.UUID, error) {
+	now := s.now()
+	var userID uuid.UUID
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, claimPasswordResetToken, arg.TokenHash, now).Scan(&userID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, usePasswordResetTokens, userID, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, setPassword, userID, arg.HashedPassword, now); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, revokeUserRefreshTokens, userID, now)
+		return err
+	})
+	return userID, err
+}
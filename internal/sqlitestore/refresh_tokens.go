@@ -0,0 +1,106 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const refreshTokenColumns = "token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by_hash"
+
+func scanRefreshToken(row scanner) (database.RefreshToken, error) {
+	var i database.RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedByHash,
+	)
+	return i, err
+}
+
+// Refresh tokens expire a month after they are issued. SQLite has no
+// INTERVAL, so the expiry is worked out in Go.
+const createRefreshToken = `
+INSERT INTO refresh_tokens (token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id)
+VALUES (?1, ?2, ?2, ?3, ?4, NULL, ?5)
+RETURNING ` + refreshTokenColumns
+
+func (s *Store) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	now := s.Now()
+	return scanRefreshToken(s.db.QueryRowContext(ctx, createRefreshToken,
+		arg.TokenHash,
+		timestamp(now),
+		arg.UserID,
+		timestamp(now.AddDate(0, 1, 0)),
+		arg.FamilyID,
+	))
+}
+
+const getRefreshToken = `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token_hash = ?1`
+
+func (s *Store) GetRefreshToken(ctx context.Context, tokenHash string) (database.RefreshToken, error) {
+	return scanRefreshToken(s.db.QueryRowContext(ctx, getRefreshToken, tokenHash))
+}
+
+const revokeRefreshToken = `
+UPDATE refresh_tokens
+SET revoked_at = ?2, updated_at = ?2
+WHERE token_hash = ?1`
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := s.db.ExecContext(ctx, revokeRefreshToken, tokenHash, s.now())
+	return err
+}
+
+const revokeForRotation = `
+UPDATE refresh_tokens
+SET revoked_at = ?3, updated_at = ?3, replaced_by_hash = ?2
+WHERE token_hash = ?1 AND revoked_at IS NULL AND expires_at > ?3
+RETURNING user_id, family_id`
+
+// RotateRefreshToken revokes the old token and issues its successor in
+// one write transaction, which SQLite serialises, so a token can only be
+// rotated once.
+func (s *Store) RotateRefreshToken(ctx context.Context, arg database.RotateRefreshTokenParams) (database.RefreshToken, error) {
+	now := s.Now()
+	var token database.RefreshToken
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var userID uuid.NullUUID
+		var familyID uuid.UUID
+		err := tx.QueryRowContext(ctx, revokeForRotation,
+			arg.OldTokenHash,
+			arg.NewTokenHash,
+			timestamp(now),
+		).Scan(&userID, &familyID)
+		if err != nil {
+			return err
+		}
+
+		token, err = scanRefreshToken(tx.QueryRowContext(ctx, createRefreshToken,
+			arg.NewTokenHash,
+			timestamp(now),
+			userID,
+			timestamp(now.AddDate(0, 1, 0)),
+			familyID,
+		))
+		return err
+	})
+	return token, err
+}
+
+const revokeRefreshTokenFamily = `
+UPDATE refresh_tokens
+SET revoked_at = ?2, updated_at = ?2
+WHERE family_id = ?1 AND revoked_at IS NULL`
+
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID, s.now())
+	return err
+}
@@ -0,0 +1,96 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const scheduledChirpColumns = "id, created_at, user_id, body, publish_at"
+
+func scanScheduledChirp(row scanner) (database.ScheduledChirp, error) {
+	var i database.ScheduledChirp
+	err := row.Scan(&i.ID, &i.CreatedAt, &i.UserID, &i.Body, &i.PublishAt)
+	return i, err
+}
+
+const createScheduledChirp = `
+INSERT INTO scheduled_chirps (id, created_at, user_id, body, publish_at)
+VALUES (?1, ?2, ?3, ?4, ?5)
+RETURNING ` + scheduledChirpColumns
+
+func (s *Store) CreateScheduledChirp(ctx context.Context, arg database.CreateScheduledChirpParams) (database.ScheduledChirp, error) {
+	return scanScheduledChirp(s.db.QueryRowContext(ctx, createScheduledChirp,
+		uuid.New(),
+		s.now(),
+		arg.UserID,
+		arg.Body,
+		timestamp(arg.PublishAt),
+	))
+}
+
+const listScheduledChirps = `
+SELECT ` + scheduledChirpColumns + ` FROM scheduled_chirps
+WHERE user_id = ?1
+ORDER BY publish_at ASC, id ASC`
+
+func (s *Store) ListScheduledChirps(ctx context.Context, userID uuid.UUID) ([]database.ScheduledChirp, error) {
+	rows, err := s.db.QueryContext(ctx, listScheduledChirps, userID)
+	return scanAll(rows, err, scanScheduledChirp)
+}
+
+const countScheduledChirps = `SELECT COUNT(*) FROM scheduled_chirps WHERE user_id = ?1`
+
+func (s *Store) CountScheduledChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, countScheduledChirps, userID).Scan(&n)
+	return n, err
+}
+
+const countScheduledChirpsBetween = `
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = ?1 AND publish_at > ?2 AND publish_at < ?3`
+
+func (s *Store) CountScheduledChirpsBetween(ctx context.Context, arg database.CountScheduledChirpsBetweenParams) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, countScheduledChirpsBetween, arg.UserID, timestamp(arg.After), timestamp(arg.Before)).Scan(&n)
+	return n, err
+}
+
+const deleteScheduledChirp = `DELETE FROM scheduled_chirps WHERE id = ?1 AND user_id = ?2`
+
+func (s *Store) DeleteScheduledChirp(ctx context.Context, arg database.DeleteScheduledChirpParams) (int64, error) {
+	result, err := s.db.ExecContext(ctx, deleteScheduledChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const publishDueChirps = `
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, thread_id)
+SELECT id, ?1, ?1, body, user_id, id
+FROM scheduled_chirps
+WHERE publish_at <= ?1
+RETURNING ` + chirpColumns
+
+const deleteDueChirps = `DELETE FROM scheduled_chirps WHERE publish_at <= ?1`
+
+// PublishDueChirps copies the due chirps across and deletes them in one
+// write transaction, which SQLite serialises.
+func (s *Store) PublishDueChirps(ctx context.Context) ([]database.Chirp, error) {
+	now := s.now()
+	var published []database.Chirp
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, publishDueChirps, now)
+		published, err = scanAll(rows, err, scanChirp)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, deleteDueChirps, now)
+		return err
+	})
+	return published, err
+}
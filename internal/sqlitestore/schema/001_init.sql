@@ -0,0 +1,131 @@
+-- +goose Up
+-- The SQLite schema starts at the PostgreSQL schema as of its migration
+-- 012. Ids are uuid strings and timestamps are UTC text with six fractional
+-- digits, written by the store so that text order is time order.
+CREATE TABLE users(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    hashed_password TEXT NOT NULL DEFAULT 'unset',
+    is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE chirps(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    body TEXT NOT NULL,
+    user_id TEXT REFERENCES users (id) ON DELETE CASCADE,
+    like_count INTEGER NOT NULL DEFAULT 0,
+    in_reply_to_id TEXT REFERENCES chirps (id) ON DELETE SET NULL,
+    thread_id TEXT NOT NULL,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    edited_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX chirps_created_at_id_idx ON chirps (created_at, id);
+CREATE INDEX chirps_user_id_created_at_id_idx ON chirps (user_id, created_at, id);
+CREATE INDEX chirps_thread_id_idx ON chirps (thread_id, created_at, id);
+
+CREATE TABLE refresh_tokens(
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP DEFAULT NULL,
+    family_id TEXT NOT NULL,
+    replaced_by_hash TEXT DEFAULT NULL
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+CREATE TABLE follows(
+    follower_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    followee_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_follower_id_created_at_idx ON follows (follower_id, created_at, followee_id);
+CREATE INDEX follows_followee_id_created_at_idx ON follows (followee_id, created_at, follower_id);
+
+CREATE TABLE chirp_likes(
+    chirp_id TEXT NOT NULL REFERENCES chirps (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_likes_user_id_idx ON chirp_likes (user_id);
+
+-- +goose StatementBegin
+CREATE TRIGGER chirp_likes_count_insert AFTER INSERT ON chirp_likes
+BEGIN
+    UPDATE chirps SET like_count = like_count + 1 WHERE id = NEW.chirp_id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER chirp_likes_count_delete AFTER DELETE ON chirp_likes
+BEGIN
+    UPDATE chirps SET like_count = like_count - 1 WHERE id = OLD.chirp_id;
+END;
+-- +goose StatementEnd
+
+CREATE TABLE chirp_revisions(
+    id TEXT PRIMARY KEY,
+    chirp_id TEXT NOT NULL REFERENCES chirps (id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    written_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_revisions_chirp_id_idx ON chirp_revisions (chirp_id, replaced_at);
+
+CREATE TABLE moderation_rules(
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('word', 'regex')),
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'reject')),
+    UNIQUE (kind, pattern)
+);
+
+-- Full-text search runs on an FTS5 index of chirp bodies, kept in step by
+-- triggers. The porter tokenizer stems English words much like
+-- to_tsvector('english', ...) does.
+CREATE VIRTUAL TABLE chirps_search USING fts5(body, chirp_id UNINDEXED, tokenize = 'porter unicode61');
+
+-- +goose StatementBegin
+CREATE TRIGGER chirps_search_insert AFTER INSERT ON chirps
+BEGIN
+    INSERT INTO chirps_search (body, chirp_id) VALUES (NEW.body, NEW.id);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER chirps_search_update AFTER UPDATE OF body ON chirps
+BEGIN
+    UPDATE chirps_search SET body = NEW.body WHERE chirp_id = OLD.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER chirps_search_delete AFTER DELETE ON chirps
+BEGIN
+    DELETE FROM chirps_search WHERE chirp_id = OLD.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE IF EXISTS chirps_search;
+DROP TABLE IF EXISTS moderation_rules;
+DROP TABLE IF EXISTS chirp_revisions;
+DROP TABLE IF EXISTS chirp_likes;
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS chirps;
+DROP TABLE IF EXISTS users;
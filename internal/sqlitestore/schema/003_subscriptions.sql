@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    end_reason TEXT CHECK (end_reason IN ('downgraded', 'expired')),
+    CHECK ((ended_at IS NULL) = (end_reason IS NULL))
+);
+
+CREATE UNIQUE INDEX subscriptions_open_idx ON subscriptions (user_id) WHERE ended_at IS NULL;
+CREATE INDEX subscriptions_user_id_idx ON subscriptions (user_id, started_at);
+
+-- Red users from before subscriptions were tracked get a month to be
+-- renewed by Polka. strftime keeps milliseconds, so the timestamps are
+-- padded to the store's six digits.
+INSERT INTO subscriptions (id, user_id, started_at, updated_at, expires_at)
+SELECT
+    lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-'
+        || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+    id,
+    updated_at,
+    strftime('%Y-%m-%d %H:%M:%f', 'now') || '000',
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+1 month') || '000'
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE IF EXISTS subscriptions;
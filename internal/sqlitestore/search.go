@@ -0,0 +1,143 @@
+package sqlitestore
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/NachoGz/chirpy/internal/database"
+)
+
+// searchFilters are the conditions SearchChirps shares with SearchChirps in
+// sql/queries/chirps.sql. ?1 is the author, ?2 and ?3 the time range.
+const searchFilters = `
+AND chirps.deleted_at IS NULL
+AND (?1 IS NULL OR chirps.user_id = ?1)
+AND (?2 IS NULL OR chirps.created_at >= ?2)
+AND (?3 IS NULL OR chirps.created_at < ?3)`
+
+// Matches are marked with control characters rather than tags, since
+// highlight() can't escape the body around them. highlightMarks swaps them
+// for <mark> tags once the body has been escaped.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+var highlightMarks = strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>")
+
+// searchMatching ranks chirps matching the FTS5 expression ?6. bm25 scores
+// better matches lower, so it is negated to sort like ts_rank.
+const searchMatching = `
+SELECT ` + chirpColumnsOf + `,
+    -bm25(chirps_search) AS rank,
+    highlight(chirps_search, 0, char(2), char(3)) AS highlight
+FROM chirps_search
+JOIN chirps ON chirps.id = chirps_search.chirp_id
+WHERE chirps_search MATCH ?6` + searchFilters
+
+// searchAll is used when a query only excludes terms, which an FTS5 match
+// can't express on its own. Nothing is highlighted and every rank is 0.
+const searchAll = `
+SELECT ` + chirpColumnsOf + `,
+    0.0 AS rank,
+    chirps.body AS highlight
+FROM chirps
+WHERE TRUE` + searchFilters
+
+// ?7 is an FTS5 expression for the excluded terms.
+const searchExcluding = `
+AND chirps.id NOT IN (SELECT chirp_id FROM chirps_search WHERE chirps_search MATCH ?7)`
+
+const searchOrder = `
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT ?4 OFFSET ?5`
+
+func (s *Store) SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error) {
+	include, exclude, err := matchExpressions(arg.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	query := searchAll
+	if include != "" {
+		query = searchMatching
+	}
+	if exclude != "" {
+		query += searchExcluding
+	}
+	query += searchOrder
+
+	rows, err := s.db.QueryContext(ctx, query,
+		arg.AuthorID,
+		nullTimestamp(arg.Since),
+		nullTimestamp(arg.Until),
+		arg.PageSize,
+		arg.PageOffset,
+		include,
+		exclude,
+	)
+	results, err := scanAll(rows, err, func(row scanner) (database.SearchChirpsRow, error) {
+		var i database.SearchChirpsRow
+		err := row.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.InReplyToID,
+			&i.ThreadID,
+			&i.DeletedAt,
+			&i.EditedAt,
+			&i.Rank,
+			&i.Highlight,
+		)
+		return i, err
+	})
+	for i := range results {
+		results[i].Highlight = highlightMarks.Replace(html.EscapeString(results[i].Highlight))
+	}
+	return results, err
+}
+
+// matchExpressions translates the subset of to_tsquery syntax the search
+// handler produces (terms joined with &, each a word, a word:* prefix or a
+// (phrase <-> of <-> words), any of them negated with !) into two FTS5
+// expressions: one a chirp must match and one it must not. Either may be
+// empty.
+func matchExpressions(q string) (include, exclude string, err error) {
+	var included, excluded []string
+	for _, part := range strings.Split(q, " & ") {
+		negate := strings.HasPrefix(part, "!")
+		part = strings.TrimPrefix(part, "!")
+
+		var words []string
+		suffix := ""
+		if strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")") {
+			words = strings.Split(part[1:len(part)-1], " <-> ")
+		} else {
+			if strings.HasSuffix(part, ":*") {
+				suffix = "*"
+				part = strings.TrimSuffix(part, ":*")
+			}
+			words = []string{part}
+		}
+		for _, word := range words {
+			// Quoting keeps words like AND or NEAR from being read as
+			// operators; the handler has already dropped anything else
+			if word == "" || strings.ContainsAny(word, `"*`) {
+				return "", "", fmt.Errorf("sqlitestore: unsupported tsquery %q", q)
+			}
+		}
+
+		term := `"` + strings.Join(words, " ") + `"` + suffix
+		if negate {
+			excluded = append(excluded, term)
+		} else {
+			included = append(included, term)
+		}
+	}
+	return strings.Join(included, " AND "), strings.Join(excluded, " OR "), nil
+}
@@ -0,0 +1,134 @@
+// Package sqlitestore implements database.Querier on SQLite, for
+// deployments that don't want to run a PostgreSQL server. It uses the pure
+// Go modernc.org/sqlite driver, so chirpy still builds without cgo.
+//
+// The queries mirror those in sql/queries. Ids are generated and
+// timestamps taken in Go rather than with gen_random_uuid() and NOW(), and
+// full-text search runs on FTS5 instead of tsvector.
+//
+// Known differences: search ranks come from bm25 and are not comparable
+// with ts_rank, and stop words are not dropped.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	_ "modernc.org/sqlite" // SQLite driver
+)
+
+//go:embed schema/*.sql
+var schemaFS embed.FS
+
+// Migrations holds the goose migrations for the SQLite schema.
+var Migrations, _ = fs.Sub(schemaFS, "schema")
+
+// timestampLayout is how TIMESTAMP columns are stored. A fixed number of
+// fractional digits keeps text comparisons in time order.
+const timestampLayout = "2006-01-02 15:04:05.000000"
+
+// Store runs every query against a SQLite database. It is safe for
+// concurrent use.
+type Store struct {
+	db *sql.DB
+
+	// Now is the clock used for NOW(). Tests may replace it before using
+	// the store.
+	Now func() time.Time
+}
+
+var _ database.Querier = (*Store)(nil)
+
+// Open opens the SQLite database file at path, creating it if needed.
+// Foreign keys are enforced, writers wait for each other instead of failing
+// with SQLITE_BUSY, and transactions take the write lock up front.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	if base, query, ok := strings.Cut(path, "?"); ok {
+		var err error
+		if params, err = url.ParseQuery(query); err != nil {
+			return nil, err
+		}
+		path = base
+	}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	return sql.Open("sqlite", path+"?"+params.Encode())
+}
+
+// New returns a store backed by db, which should come from Open and have
+// Migrations applied.
+func New(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+		Now: func() time.Time {
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+	}
+}
+
+// now returns the current time formatted for a TIMESTAMP column.
+func (s *Store) now() string {
+	return timestamp(s.Now())
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// nullTimestamp formats t for a nullable TIMESTAMP parameter.
+func nullTimestamp(t sql.NullTime) any {
+	if !t.Valid {
+		return nil
+	}
+	return timestamp(t.Time)
+}
+
+// inTx runs f in a transaction, committing if it returns no error.
+func (s *Store) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAll collects every row of a query with scan, returning an empty
+// slice rather than nil when there are none.
+func scanAll[T any](rows *sql.Rows, err error, scan func(scanner) (T, error)) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,136 @@
+package sqlitestore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/NachoGz/chirpy/internal/storetest"
+	"github.com/pressly/goose/v3"
+)
+
+func openStore(t *testing.T, now func() time.Time) database.Querier {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := goose.NewProvider(goose.DialectSQLite3, db, Migrations)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	s := New(db)
+	s.Now = now
+	return s
+}
+
+func TestContract(t *testing.T) {
+	storetest.Run(t, openStore)
+}
+
+func TestMigrateDown(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := goose.NewProvider(goose.DialectSQLite3, db, Migrations)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+	if _, err := migrator.DownTo(ctx, 0); err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+	var tables int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'goose%' AND name NOT LIKE 'sqlite%'").Scan(&tables)
+	if tables != 0 {
+		t.Errorf("expected every table to be dropped, %d left", tables)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	cases := map[string][2]string{
+		"knock":                   {`"knock"`, ""},
+		"knock:*":                 {`"knock"*`, ""},
+		"(who <-> knocks) & name": {`"who knocks" AND "name"`, ""},
+		"say & !name & !knock:*":  {`"say"`, `"name" OR "knock"*`},
+		"!and":                    {"", `"and"`},
+	}
+	for query, expected := range cases {
+		include, exclude, err := matchExpressions(query)
+		if err != nil {
+			t.Errorf("%q: %v", query, err)
+			continue
+		}
+		if include != expected[0] || exclude != expected[1] {
+			t.Errorf("%q: expected %q and %q, got %q and %q", query, expected[0], expected[1], include, exclude)
+		}
+	}
+
+	for _, query := range []string{"", "a & ", `"quoted"`} {
+		if _, _, err := matchExpressions(query); err == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+	}
+}
+
+func TestSubscriptionBackfill(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := goose.NewProvider(goose.DialectSQLite3, db, Migrations)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.UpTo(ctx, 2); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+	s := New(db)
+	walt, err := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@breakingbad.com", HashedPassword: "x", IsChirpyRed: true})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := s.CreateUser(ctx, database.CreateUserParams{Email: "jesse@breakingbad.com", HashedPassword: "x"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	history, err := s.GetSubscriptionsByUser(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("failed to get subscriptions: %v", err)
+	}
+	if len(history) != 1 || history[0].EndedAt.Valid || !history[0].StartedAt.Equal(walt.UpdatedAt) {
+		t.Fatalf("expected an open subscription for the Red user, got %+v", history)
+	}
+	if expiresIn := time.Until(history[0].ExpiresAt); expiresIn < 27*24*time.Hour || expiresIn > 32*24*time.Hour {
+		t.Errorf("expected the subscription to run for a month, got %s", expiresIn)
+	}
+	if history[0].ID.Version() != 4 {
+		t.Errorf("expected a v4 uuid, got %s", history[0].ID)
+	}
+	var subscriptions int
+	db.QueryRow("SELECT COUNT(*) FROM subscriptions").Scan(&subscriptions)
+	if subscriptions != 1 {
+		t.Errorf("expected only Red users to get a subscription, got %d", subscriptions)
+	}
+}
@@ -0,0 +1,163 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const subscriptionColumns = "id, user_id, started_at, updated_at, expires_at, ended_at, end_reason"
+
+func scanSubscription(row scanner) (database.Subscription, error) {
+	var i database.Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.StartedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.EndedAt,
+		&i.EndReason,
+	)
+	return i, err
+}
+
+const claimPolkaEvent = `
+INSERT INTO polka_events (id, event, received_at)
+SELECT ?1, ?2, ?4 FROM users WHERE id = ?3
+ON CONFLICT (id) DO NOTHING`
+
+// claimPolkaEventTx records the event inside tx, or returns sql.ErrNoRows
+// when it was recorded before or the user doesn't exist. The claim commits
+// only if the rest of tx does.
+func claimPolkaEventTx(ctx context.Context, tx *sql.Tx, id, event string, userID uuid.UUID, now string) error {
+	result, err := tx.ExecContext(ctx, claimPolkaEvent, id, event, userID, now)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const setChirpyRed = `
+UPDATE users
+SET is_chirpy_red = ?2, updated_at = ?3
+WHERE id = ?1`
+
+const renewOpenSubscription = `
+UPDATE subscriptions
+SET expires_at = MAX(expires_at, ?2), updated_at = ?3
+WHERE user_id = ?1 AND ended_at IS NULL
+RETURNING ` + subscriptionColumns
+
+const openSubscription = `
+INSERT INTO subscriptions (id, user_id, started_at, updated_at, expires_at)
+VALUES (?1, ?2, ?3, ?3, ?4)
+RETURNING ` + subscriptionColumns
+
+// RenewSubscription records the event, marks the user Red and then
+// extends or opens their subscription, all in one transaction. Timestamps
+// are fixed-width text, so MAX picks the later expiry.
+func (s *Store) RenewSubscription(ctx context.Context, arg database.RenewSubscriptionParams) (database.RenewSubscriptionRow, error) {
+	now := s.now()
+	var subscription database.Subscription
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := claimPolkaEventTx(ctx, tx, arg.EventID, arg.Event, arg.UserID, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, setChirpyRed, arg.UserID, true, now); err != nil {
+			return err
+		}
+
+		var err error
+		subscription, err = scanSubscription(tx.QueryRowContext(ctx, renewOpenSubscription,
+			arg.UserID,
+			timestamp(arg.ExpiresAt),
+			now,
+		))
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		subscription, err = scanSubscription(tx.QueryRowContext(ctx, openSubscription,
+			uuid.New(),
+			arg.UserID,
+			now,
+			timestamp(arg.ExpiresAt),
+		))
+		return err
+	})
+	return database.RenewSubscriptionRow(subscription), err
+}
+
+const cancelSubscription = `
+UPDATE subscriptions
+SET ended_at = ?2, end_reason = 'downgraded', updated_at = ?2
+WHERE user_id = ?1 AND ended_at IS NULL`
+
+func (s *Store) CancelSubscription(ctx context.Context, arg database.CancelSubscriptionParams) (int64, error) {
+	now := s.now()
+	var n int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := claimPolkaEventTx(ctx, tx, arg.EventID, arg.Event, arg.UserID, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, cancelSubscription, arg.UserID, now); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, setChirpyRed, arg.UserID, false, now)
+		if err != nil {
+			return err
+		}
+		n, err = result.RowsAffected()
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return n, err
+}
+
+const expireSubscriptions = `
+UPDATE subscriptions
+SET ended_at = ?2, end_reason = 'expired', updated_at = ?2
+WHERE ended_at IS NULL AND expires_at < ?1
+RETURNING ` + subscriptionColumns
+
+func (s *Store) ExpireSubscriptions(ctx context.Context, cutoff time.Time) ([]database.ExpireSubscriptionsRow, error) {
+	now := s.now()
+	var expired []database.ExpireSubscriptionsRow
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, expireSubscriptions, timestamp(cutoff), now)
+		subscriptions, err := scanAll(rows, err, scanSubscription)
+		if err != nil {
+			return err
+		}
+
+		for _, subscription := range subscriptions {
+			if _, err := tx.ExecContext(ctx, setChirpyRed, subscription.UserID, false, now); err != nil {
+				return err
+			}
+			expired = append(expired, database.ExpireSubscriptionsRow(subscription))
+		}
+		return nil
+	})
+	return expired, err
+}
+
+const getSubscriptionsByUser = `
+SELECT ` + subscriptionColumns + ` FROM subscriptions
+WHERE user_id = ?1
+ORDER BY started_at DESC, id DESC`
+
+func (s *Store) GetSubscriptionsByUser(ctx context.Context, userID uuid.UUID) ([]database.Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, getSubscriptionsByUser, userID)
+	return scanAll(rows, err, scanSubscription)
+}
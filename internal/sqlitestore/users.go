@@ -0,0 +1,72 @@
+package sqlitestore
+
+import (
+	"context"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const userColumns = "id, created_at, updated_at, email, hashed_password, is_chirpy_red"
+
+func scanUser(row scanner) (database.User, error) {
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const createUser = `
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+VALUES (?1, ?2, ?2, ?3, ?4, ?5)
+RETURNING ` + userColumns
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, createUser,
+		uuid.New(),
+		s.now(),
+		arg.Email,
+		arg.HashedPassword,
+		arg.IsChirpyRed,
+	))
+}
+
+const deleteAllUsers = `DELETE FROM users`
+
+func (s *Store) DeleteAllUsers(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, deleteAllUsers)
+	return err
+}
+
+const getUserByEmail = `SELECT ` + userColumns + ` FROM users WHERE email = ?1`
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, getUserByEmail, email))
+}
+
+const getUserByID = `SELECT ` + userColumns + ` FROM users WHERE id = ?1`
+
+func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, getUserByID, id))
+}
+
+const changeEmailAndPassword = `
+UPDATE users
+SET email = ?2, hashed_password = ?3, updated_at = ?4
+WHERE id = ?1
+RETURNING ` + userColumns
+
+func (s *Store) ChangeEmailAndPassword(ctx context.Context, arg database.ChangeEmailAndPasswordParams) (database.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, changeEmailAndPassword,
+		arg.ID,
+		arg.Email,
+		arg.HashedPassword,
+		s.now(),
+	))
+}
@@ -0,0 +1,821 @@
+// Package storetest is a contract test suite for implementations of
+// database.Querier, so every backend is held to the semantics of the
+// PostgreSQL queries.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// OpenFunc returns an empty store for one test. Stores that take
+// timestamps from Go should use now as their clock; it advances a second
+// per call so rows get distinct, ordered timestamps.
+type OpenFunc func(t *testing.T, now func() time.Time) database.Querier
+
+// Run runs the whole suite against the stores open returns.
+func Run(t *testing.T, open OpenFunc) {
+	tests := map[string]func(*testing.T, database.Querier){
+		"Users":              testUsers,
+		"ListChirpsPages":    testListChirpsPages,
+		"Threads":            testThreads,
+		"Revisions":          testRevisions,
+		"RotateRefreshToken": testRotateRefreshToken,
+		"LikesAndFollows":    testLikesAndFollows,
+		"ConcurrentLikes":    testConcurrentLikes,
+		"SearchChirps":       testSearchChirps,
+		"ModerationRules":    testModerationRules,
+		"PolkaEvents":        testPolkaEvents,
+		"Subscriptions":      testSubscriptions,
+		"ScheduledChirps":    testScheduledChirps,
+		"PasswordReset":      testPasswordReset,
+		"DeleteAllUsers":     testDeleteAllUsers,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			var mu sync.Mutex
+			clock := func() time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				now = now.Add(time.Second)
+				return now
+			}
+			test(t, open(t, clock))
+		})
+	}
+}
+
+func mustCreateUser(t *testing.T, s database.Querier, email string) database.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{Email: email, HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func mustCreateChirp(t *testing.T, s database.Querier, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	chirp, err := s.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:   body,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create chirp: %v", err)
+	}
+	return chirp
+}
+
+func bodies(chirps []database.Chirp) []string {
+	bodies := []string{}
+	for _, chirp := range chirps {
+		bodies = append(bodies, chirp.Body)
+	}
+	return bodies
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testUsers(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "walt@breakingbad.com")
+	if user.ID == uuid.Nil || user.IsChirpyRed || user.CreatedAt.IsZero() {
+		t.Errorf("unexpected new user %+v", user)
+	}
+
+	if _, err := s.CreateUser(ctx, database.CreateUserParams{Email: "walt@breakingbad.com"}); err == nil {
+		t.Error("expected a duplicate email to be refused")
+	}
+	if _, err := s.GetUserByEmail(ctx, "jesse@breakingbad.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	if _, err := s.GetUserByID(ctx, uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	changed, err := s.ChangeEmailAndPassword(ctx, database.ChangeEmailAndPasswordParams{
+		ID:             user.ID,
+		Email:          "heisenberg@breakingbad.com",
+		HashedPassword: "y",
+	})
+	if err != nil {
+		t.Fatalf("failed to change email: %v", err)
+	}
+	if changed.Email != "heisenberg@breakingbad.com" || changed.HashedPassword != "y" || !changed.UpdatedAt.After(user.UpdatedAt) {
+		t.Errorf("unexpected changed user %+v", changed)
+	}
+
+	got, err := s.GetUserByEmail(ctx, "heisenberg@breakingbad.com")
+	if err != nil || got.ID != user.ID {
+		t.Errorf("expected the changed user, got %+v, %v", got, err)
+	}
+	if !got.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("timestamps don't round-trip: created %v, read back %v", user.CreatedAt, got.CreatedAt)
+	}
+}
+
+func testListChirpsPages(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@breakingbad.com")
+	jesse := mustCreateUser(t, s, "jesse@breakingbad.com")
+	var chirps []database.Chirp
+	for _, body := range []string{"one", "two", "three", "four"} {
+		chirps = append(chirps, mustCreateChirp(t, s, walt.ID, body))
+	}
+	mustCreateChirp(t, s, jesse.ID, "yo")
+
+	page, err := s.ListChirpsBefore(ctx, database.ListChirpsBeforeParams{
+		AuthorID: uuid.NullUUID{UUID: walt.ID, Valid: true},
+		PageSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to list chirps: %v", err)
+	}
+	if got := bodies(page); !equal(got, []string{"four", "three"}) {
+		t.Fatalf("unexpected first page %v", got)
+	}
+
+	last := page[len(page)-1]
+	page, _ = s.ListChirpsBefore(ctx, database.ListChirpsBeforeParams{
+		CursorCreatedAt: sql.NullTime{Time: last.CreatedAt, Valid: true},
+		CursorID:        uuid.NullUUID{UUID: last.ID, Valid: true},
+		PageSize:        10,
+	})
+	if got := bodies(page); !equal(got, []string{"two", "one"}) {
+		t.Fatalf("unexpected second page %v", got)
+	}
+
+	page, _ = s.ListChirpsAfter(ctx, database.ListChirpsAfterParams{
+		CursorCreatedAt: sql.NullTime{Time: chirps[1].CreatedAt, Valid: true},
+		CursorID:        uuid.NullUUID{UUID: chirps[1].ID, Valid: true},
+		PageSize:        10,
+	})
+	if got := bodies(page); !equal(got, []string{"three", "four", "yo"}) {
+		t.Errorf("unexpected page after a cursor %v", got)
+	}
+
+	if _, err := s.DeleteChirp(ctx, chirps[0].ID); err != nil {
+		t.Fatalf("failed to delete chirp: %v", err)
+	}
+	page, _ = s.ListChirpsAfter(ctx, database.ListChirpsAfterParams{PageSize: 10})
+	if got := bodies(page); !equal(got, []string{"two", "three", "four", "yo"}) {
+		t.Errorf("expected the deleted chirp to be skipped, got %v", got)
+	}
+	if _, err := s.GetChirpByID(ctx, chirps[0].ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a deleted chirp, got %v", err)
+	}
+	if _, err := s.DeleteChirp(ctx, chirps[0].ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows deleting twice, got %v", err)
+	}
+}
+
+func testThreads(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "walt@breakingbad.com")
+	root := mustCreateChirp(t, s, user.ID, "Say my name")
+	if root.ThreadID != root.ID || root.InReplyToID.Valid {
+		t.Errorf("expected a root chirp to start its own thread, got %+v", root)
+	}
+
+	reply, err := s.CreateChirp(ctx, database.CreateChirpParams{
+		Body:        "Heisenberg",
+		UserID:      uuid.NullUUID{UUID: user.ID, Valid: true},
+		InReplyToID: uuid.NullUUID{UUID: root.ID, Valid: true},
+		ThreadID:    uuid.NullUUID{UUID: root.ThreadID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+	if reply.ThreadID != root.ID || reply.InReplyToID.UUID != root.ID {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+	if threadID, err := s.GetChirpThreadID(ctx, reply.ID); err != nil || threadID != root.ID {
+		t.Errorf("expected thread %v, got %v, %v", root.ID, threadID, err)
+	}
+
+	if err := s.LikeChirp(ctx, database.LikeChirpParams{ChirpID: root.ID, UserID: user.ID}); err != nil {
+		t.Fatalf("failed to like chirp: %v", err)
+	}
+	if _, err := s.DeleteChirp(ctx, root.ID); err != nil {
+		t.Fatalf("failed to delete chirp: %v", err)
+	}
+	thread, err := s.GetThread(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("failed to get thread: %v", err)
+	}
+	if len(thread) != 2 || thread[0].ID != root.ID || thread[1].ID != reply.ID {
+		t.Fatalf("expected the tombstone and the reply, got %+v", thread)
+	}
+	if !thread[0].DeletedAt.Valid || thread[0].Body != "" || thread[0].LikeCount != 0 {
+		t.Errorf("expected a tombstone, got %+v", thread[0])
+	}
+	liked, err := s.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{UserID: user.ID, ChirpIds: []uuid.UUID{root.ID}})
+	if err != nil || len(liked) != 0 {
+		t.Errorf("expected the tombstone's likes to be gone, got %v, %v", liked, err)
+	}
+}
+
+func testRevisions(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "walt@breakingbad.com")
+	chirp := mustCreateChirp(t, s, user.ID, "first")
+
+	for _, body := range []string{"second", "third"} {
+		if _, err := s.UpdateChirpBody(ctx, database.UpdateChirpBodyParams{ID: chirp.ID, Body: body}); err != nil {
+			t.Fatalf("failed to edit chirp: %v", err)
+		}
+	}
+	edited, _ := s.GetChirpByID(ctx, chirp.ID)
+	if edited.Body != "third" || !edited.EditedAt.Valid {
+		t.Errorf("unexpected edited chirp %+v", edited)
+	}
+
+	revisions, err := s.ListChirpRevisions(ctx, chirp.ID)
+	if err != nil {
+		t.Fatalf("failed to list revisions: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Body != "first" || revisions[1].Body != "second" {
+		t.Fatalf("unexpected revisions %+v", revisions)
+	}
+	if !revisions[0].WrittenAt.Equal(chirp.CreatedAt) || !revisions[1].WrittenAt.Equal(revisions[0].ReplacedAt) {
+		t.Errorf("revision times don't line up: %+v", revisions)
+	}
+
+	if _, err := s.DeleteChirp(ctx, chirp.ID); err != nil {
+		t.Fatalf("failed to delete chirp: %v", err)
+	}
+	if _, err := s.UpdateChirpBody(ctx, database.UpdateChirpBodyParams{ID: chirp.ID, Body: "fourth"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows editing a deleted chirp, got %v", err)
+	}
+	if revisions, _ := s.ListChirpRevisions(ctx, chirp.ID); len(revisions) != 0 {
+		t.Errorf("expected revisions to go with the chirp, got %+v", revisions)
+	}
+}
+
+func testRotateRefreshToken(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "walt@breakingbad.com")
+	family := uuid.New()
+
+	created, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: "a",
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+		FamilyID:  family,
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if expiry := created.ExpiresAt.Sub(created.CreatedAt); expiry < 28*24*time.Hour || expiry > 31*24*time.Hour {
+		t.Errorf("expected the token to last a month, got %v", expiry)
+	}
+
+	rotated, err := s.RotateRefreshToken(ctx, database.RotateRefreshTokenParams{OldTokenHash: "a", NewTokenHash: "b"})
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if rotated.TokenHash != "b" || rotated.FamilyID != family || rotated.UserID.UUID != user.ID || rotated.RevokedAt.Valid {
+		t.Errorf("unexpected rotated token %+v", rotated)
+	}
+
+	if _, err := s.RotateRefreshToken(ctx, database.RotateRefreshTokenParams{OldTokenHash: "a", NewTokenHash: "c"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a used token to be refused, got %v", err)
+	}
+	if _, err := s.GetRefreshToken(ctx, "c"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a refused rotation to issue nothing, got %v", err)
+	}
+	old, _ := s.GetRefreshToken(ctx, "a")
+	if !old.RevokedAt.Valid || old.ReplacedByHash.String != "b" {
+		t.Errorf("expected the old token to be revoked and point at its replacement, got %+v", old)
+	}
+
+	if err := s.RevokeRefreshTokenFamily(ctx, family); err != nil {
+		t.Fatalf("failed to revoke family: %v", err)
+	}
+	if token, _ := s.GetRefreshToken(ctx, "b"); !token.RevokedAt.Valid {
+		t.Error("expected the whole family to be revoked")
+	}
+
+	if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: "d",
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+		FamilyID:  uuid.New(),
+	}); err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if err := s.RevokeRefreshToken(ctx, "d"); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if _, err := s.RotateRefreshToken(ctx, database.RotateRefreshTokenParams{OldTokenHash: "d", NewTokenHash: "e"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a revoked token to be refused, got %v", err)
+	}
+}
+
+func testLikesAndFollows(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	author := mustCreateUser(t, s, "walt@breakingbad.com")
+	fan := mustCreateUser(t, s, "jesse@breakingbad.com")
+	chirp := mustCreateChirp(t, s, author.ID, "Say my name")
+	other := mustCreateChirp(t, s, author.ID, "I am the danger")
+
+	for i := 0; i < 2; i++ {
+		if err := s.LikeChirp(ctx, database.LikeChirpParams{ChirpID: chirp.ID, UserID: fan.ID}); err != nil {
+			t.Fatalf("failed to like: %v", err)
+		}
+	}
+	if got, _ := s.GetChirpByID(ctx, chirp.ID); got.LikeCount != 1 {
+		t.Errorf("expected likes to be idempotent, got count %d", got.LikeCount)
+	}
+	liked, err := s.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+		UserID:   fan.ID,
+		ChirpIds: []uuid.UUID{chirp.ID, other.ID},
+	})
+	if err != nil {
+		t.Fatalf("failed to list likes: %v", err)
+	}
+	if len(liked) != 1 || liked[0] != chirp.ID {
+		t.Errorf("expected only %v to be liked, got %v", chirp.ID, liked)
+	}
+	if err := s.UnlikeChirp(ctx, database.UnlikeChirpParams{ChirpID: chirp.ID, UserID: fan.ID}); err != nil {
+		t.Fatalf("failed to unlike: %v", err)
+	}
+	if got, _ := s.GetChirpByID(ctx, chirp.ID); got.LikeCount != 0 {
+		t.Errorf("expected unliking to drop the count, got %d", got.LikeCount)
+	}
+
+	if err := s.FollowUser(ctx, database.FollowUserParams{FollowerID: fan.ID, FolloweeID: fan.ID}); err == nil {
+		t.Error("expected following yourself to be refused")
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.FollowUser(ctx, database.FollowUserParams{FollowerID: fan.ID, FolloweeID: author.ID}); err != nil {
+			t.Fatalf("failed to follow: %v", err)
+		}
+	}
+	timeline, _ := s.GetTimelineBefore(ctx, database.GetTimelineBeforeParams{UserID: fan.ID, PageSize: 10})
+	if got := bodies(timeline); !equal(got, []string{"I am the danger", "Say my name"}) {
+		t.Errorf("unexpected timeline %v", got)
+	}
+	timeline, _ = s.GetTimelineAfter(ctx, database.GetTimelineAfterParams{
+		UserID:          fan.ID,
+		CursorCreatedAt: sql.NullTime{Time: chirp.CreatedAt, Valid: true},
+		CursorID:        uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		PageSize:        10,
+	})
+	if got := bodies(timeline); !equal(got, []string{"I am the danger"}) {
+		t.Errorf("unexpected timeline after a cursor %v", got)
+	}
+
+	followers, _ := s.ListFollowersBefore(ctx, database.ListFollowersBeforeParams{UserID: author.ID, PageSize: 10})
+	if len(followers) != 1 || followers[0].FollowerID != fan.ID {
+		t.Errorf("unexpected followers %+v", followers)
+	}
+	following, _ := s.ListFollowingAfter(ctx, database.ListFollowingAfterParams{UserID: fan.ID, PageSize: 10})
+	if len(following) != 1 || following[0].FolloweeID != author.ID {
+		t.Errorf("unexpected following %+v", following)
+	}
+
+	if err := s.UnfollowUser(ctx, database.UnfollowUserParams{FollowerID: fan.ID, FolloweeID: author.ID}); err != nil {
+		t.Fatalf("failed to unfollow: %v", err)
+	}
+	if followers, _ := s.ListFollowersAfter(ctx, database.ListFollowersAfterParams{UserID: author.ID, PageSize: 10}); len(followers) != 0 {
+		t.Errorf("expected no followers, got %+v", followers)
+	}
+}
+
+func testConcurrentLikes(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	author := mustCreateUser(t, s, "walt@breakingbad.com")
+	chirp := mustCreateChirp(t, s, author.ID, "Say my name")
+
+	var fans []database.User
+	for i := 0; i < 20; i++ {
+		fans = append(fans, mustCreateUser(t, s, uuid.NewString()+"@example.com"))
+	}
+
+	var wg sync.WaitGroup
+	for _, fan := range fans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.LikeChirp(ctx, database.LikeChirpParams{ChirpID: chirp.ID, UserID: fan.ID}); err != nil {
+				t.Errorf("failed to like: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := s.GetChirpByID(ctx, chirp.ID); got.LikeCount != int32(len(fans)) {
+		t.Errorf("expected %d likes, got %d", len(fans), got.LikeCount)
+	}
+}
+
+// testSearchChirps sticks to words without stop words or stemming
+// ambiguity, which backends are free to handle differently.
+func testSearchChirps(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@breakingbad.com")
+	jesse := mustCreateUser(t, s, "jesse@breakingbad.com")
+	meth := mustCreateChirp(t, s, walt.ID, "Blue meth cooking tonight")
+	pancakes := mustCreateChirp(t, s, jesse.ID, "Cooking pancakes tonight")
+	mustCreateChirp(t, s, walt.ID, "Saul called about the car wash")
+	deleted := mustCreateChirp(t, s, walt.ID, "Blue meth again")
+	if _, err := s.DeleteChirp(ctx, deleted.ID); err != nil {
+		t.Fatalf("failed to delete chirp: %v", err)
+	}
+
+	cases := map[string]int{
+		"meth":                   1,
+		"cook:*":                 2,
+		"(cooking <-> tonight)":  1,
+		"tonight & !pancakes":    1,
+		"!tonight":               1,
+		"wash & saul":            1,
+		"pancakes & meth":        0,
+		"(blue <-> meth) & !car": 1,
+	}
+	for query, expected := range cases {
+		rows, err := s.SearchChirps(ctx, database.SearchChirpsParams{Query: query, PageSize: 10})
+		if err != nil {
+			t.Fatalf("%q: %v", query, err)
+		}
+		if len(rows) != expected {
+			t.Errorf("%q: expected %d results, got %d", query, expected, len(rows))
+		}
+	}
+
+	rows, _ := s.SearchChirps(ctx, database.SearchChirpsParams{Query: "meth", PageSize: 10})
+	if len(rows) == 1 && rows[0].Highlight != "Blue <mark>meth</mark> cooking tonight" {
+		t.Errorf("unexpected highlight %q", rows[0].Highlight)
+	}
+	mustCreateChirp(t, s, walt.ID, `<script>alert("Heisenberg")</script> & friends`)
+	rows, _ = s.SearchChirps(ctx, database.SearchChirpsParams{Query: "heisenberg", PageSize: 10})
+	if len(rows) != 1 || rows[0].Highlight != "&lt;script&gt;alert(&#34;<mark>Heisenberg</mark>&#34;)&lt;/script&gt; &amp; friends" {
+		t.Errorf("expected the highlight to be escaped, got %+v", rows)
+	}
+
+	rows, _ = s.SearchChirps(ctx, database.SearchChirpsParams{
+		Query:    "tonight",
+		AuthorID: uuid.NullUUID{UUID: jesse.ID, Valid: true},
+		PageSize: 10,
+	})
+	if len(rows) != 1 || rows[0].ID != pancakes.ID {
+		t.Errorf("expected only jesse's chirp, got %+v", rows)
+	}
+	rows, _ = s.SearchChirps(ctx, database.SearchChirpsParams{
+		Query:    "tonight",
+		Until:    sql.NullTime{Time: pancakes.CreatedAt, Valid: true},
+		PageSize: 10,
+	})
+	if len(rows) != 1 || rows[0].ID != meth.ID {
+		t.Errorf("expected only the earlier chirp, got %+v", rows)
+	}
+	rows, _ = s.SearchChirps(ctx, database.SearchChirpsParams{
+		Query:    "tonight",
+		Since:    sql.NullTime{Time: pancakes.CreatedAt, Valid: true},
+		PageSize: 10,
+	})
+	if len(rows) != 1 || rows[0].ID != pancakes.ID {
+		t.Errorf("expected only the later chirp, got %+v", rows)
+	}
+
+	first, _ := s.SearchChirps(ctx, database.SearchChirpsParams{Query: "tonight", PageSize: 1})
+	second, _ := s.SearchChirps(ctx, database.SearchChirpsParams{Query: "tonight", PageSize: 1, PageOffset: 1})
+	if len(first) != 1 || len(second) != 1 || first[0].ID == second[0].ID {
+		t.Errorf("expected two distinct pages, got %+v and %+v", first, second)
+	}
+}
+
+func testModerationRules(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	word, err := s.CreateModerationRule(ctx, database.CreateModerationRuleParams{Kind: "word", Pattern: "kerfuffle", Action: "mask"})
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if _, err := s.CreateModerationRule(ctx, database.CreateModerationRuleParams{Kind: "regex", Pattern: "sh[a]rbert", Action: "reject"}); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+
+	if _, err := s.CreateModerationRule(ctx, database.CreateModerationRuleParams{Kind: "word", Pattern: "kerfuffle", Action: "reject"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected no row for a duplicate rule, got %v", err)
+	}
+	if _, err := s.CreateModerationRule(ctx, database.CreateModerationRuleParams{Kind: "glob", Pattern: "*", Action: "mask"}); err == nil {
+		t.Error("expected an unknown kind to be refused")
+	}
+
+	rules, err := s.ListModerationRules(ctx)
+	if err != nil {
+		t.Fatalf("failed to list rules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != word.ID || rules[1].Kind != "regex" {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	if n, err := s.DeleteModerationRule(ctx, word.ID); err != nil || n != 1 {
+		t.Errorf("expected to delete one rule, got %d, %v", n, err)
+	}
+	if n, err := s.DeleteModerationRule(ctx, word.ID); err != nil || n != 0 {
+		t.Errorf("expected to delete nothing the second time, got %d, %v", n, err)
+	}
+}
+
+func testPolkaEvents(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@breakingbad.com")
+	january := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	upgrade := database.RenewSubscriptionParams{EventID: "evt_1", Event: "user.upgraded", UserID: walt.ID, ExpiresAt: january}
+
+	if _, err := s.RenewSubscription(ctx, database.RenewSubscriptionParams{EventID: "evt_1", Event: "user.upgraded", UserID: uuid.New(), ExpiresAt: january}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected no rows for a missing user, got %v", err)
+	}
+	// The failed delivery recorded nothing, so the event can still apply
+	if _, err := s.RenewSubscription(ctx, upgrade); err != nil {
+		t.Fatalf("failed to apply the event: %v", err)
+	}
+	redelivery := upgrade
+	redelivery.ExpiresAt = january.AddDate(1, 0, 0)
+	if _, err := s.RenewSubscription(ctx, redelivery); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a redelivery not to apply, got %v", err)
+	}
+	if history, _ := s.GetSubscriptionsByUser(ctx, walt.ID); len(history) != 1 || !history[0].ExpiresAt.Equal(january) {
+		t.Errorf("expected the redelivery to leave the subscription alone, got %+v", history)
+	}
+
+	downgrade := database.CancelSubscriptionParams{EventID: "evt_2", Event: "user.downgraded", UserID: walt.ID}
+	if n, err := s.CancelSubscription(ctx, downgrade); err != nil || n != 1 {
+		t.Fatalf("expected to apply the downgrade, got %d, %v", n, err)
+	}
+	if n, err := s.CancelSubscription(ctx, downgrade); err != nil || n != 0 {
+		t.Errorf("expected a redelivered downgrade not to apply, got %d, %v", n, err)
+	}
+	// An upgrade reusing an applied event's id is a redelivery too
+	if _, err := s.RenewSubscription(ctx, database.RenewSubscriptionParams{EventID: "evt_2", Event: "user.upgraded", UserID: walt.ID, ExpiresAt: january}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a used event id not to apply, got %v", err)
+	}
+	if user, _ := s.GetUserByID(ctx, walt.ID); user.IsChirpyRed {
+		t.Error("expected walt to stay downgraded")
+	}
+}
+
+func testSubscriptions(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@breakingbad.com")
+	jesse := mustCreateUser(t, s, "jesse@breakingbad.com")
+	isRed := func(user database.User) bool {
+		got, err := s.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		return got.IsChirpyRed
+	}
+	renew := func(userID uuid.UUID, expiresAt time.Time) (database.RenewSubscriptionRow, error) {
+		return s.RenewSubscription(ctx, database.RenewSubscriptionParams{EventID: uuid.NewString(), Event: "user.renewed", UserID: userID, ExpiresAt: expiresAt})
+	}
+	cancel := func(userID uuid.UUID) (int64, error) {
+		return s.CancelSubscription(ctx, database.CancelSubscriptionParams{EventID: uuid.NewString(), Event: "user.downgraded", UserID: userID})
+	}
+	january := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	february := january.AddDate(0, 1, 0)
+
+	first, err := renew(walt.ID, january)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if !first.ExpiresAt.Equal(january) || first.EndedAt.Valid || !isRed(walt) {
+		t.Errorf("unexpected subscription %+v", first)
+	}
+	// A renewal can only push the expiry back
+	for _, expiresAt := range []time.Time{january.AddDate(0, 0, -1), february} {
+		renewed, err := renew(walt.ID, expiresAt)
+		if err != nil {
+			t.Fatalf("failed to renew: %v", err)
+		}
+		if renewed.ID != first.ID {
+			t.Errorf("expected the open subscription to be renewed, got %+v", renewed)
+		}
+	}
+	if _, err := renew(uuid.New(), january); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected no rows subscribing a missing user, got %v", err)
+	}
+
+	if _, err := renew(jesse.ID, january); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	expired, err := s.ExpireSubscriptions(ctx, january.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to expire: %v", err)
+	}
+	if len(expired) != 1 || expired[0].UserID != jesse.ID || expired[0].EndReason.String != "expired" || !expired[0].EndedAt.Valid {
+		t.Fatalf("expected only jesse's subscription to expire, got %+v", expired)
+	}
+	if isRed(jesse) || !isRed(walt) {
+		t.Error("expected only jesse to lose Chirpy Red")
+	}
+	if expired, err := s.ExpireSubscriptions(ctx, january.Add(time.Hour)); err != nil || len(expired) != 0 {
+		t.Errorf("expected nothing left to expire, got %+v, %v", expired, err)
+	}
+
+	if n, err := cancel(walt.ID); err != nil || n != 1 {
+		t.Fatalf("expected to cancel, got %d, %v", n, err)
+	}
+	if isRed(walt) {
+		t.Error("expected walt to lose Chirpy Red")
+	}
+	if n, err := cancel(uuid.New()); err != nil || n != 0 {
+		t.Errorf("expected nothing to cancel for a missing user, got %d, %v", n, err)
+	}
+
+	second, err := renew(walt.ID, february)
+	if err != nil {
+		t.Fatalf("failed to resubscribe: %v", err)
+	}
+	history, err := s.GetSubscriptionsByUser(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 2 || history[0].ID != second.ID || history[1].ID != first.ID {
+		t.Fatalf("expected both subscriptions newest first, got %+v", history)
+	}
+	if history[1].EndReason.String != "downgraded" || !history[1].ExpiresAt.Equal(february) {
+		t.Errorf("unexpected ended subscription %+v", history[1])
+	}
+	if history, err := s.GetSubscriptionsByUser(ctx, uuid.New()); err != nil || len(history) != 0 {
+		t.Errorf("expected no history for a missing user, got %+v, %v", history, err)
+	}
+}
+
+func testScheduledChirps(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@breakingbad.com")
+	jesse := mustCreateUser(t, s, "jesse@breakingbad.com")
+	schedule := func(body string, publishAt time.Time) database.ScheduledChirp {
+		t.Helper()
+		scheduled, err := s.CreateScheduledChirp(ctx, database.CreateScheduledChirpParams{UserID: walt.ID, Body: body, PublishAt: publishAt})
+		if err != nil {
+			t.Fatalf("failed to schedule chirp: %v", err)
+		}
+		return scheduled
+	}
+	later := schedule("later", time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	due := schedule("due", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	cancelled := schedule("cancelled", time.Date(2100, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	if n, err := s.DeleteScheduledChirp(ctx, database.DeleteScheduledChirpParams{ID: cancelled.ID, UserID: jesse.ID}); err != nil || n != 0 {
+		t.Errorf("expected only the author to cancel, got %d, %v", n, err)
+	}
+	if n, err := s.DeleteScheduledChirp(ctx, database.DeleteScheduledChirpParams{ID: cancelled.ID, UserID: walt.ID}); err != nil || n != 1 {
+		t.Errorf("expected to cancel, got %d, %v", n, err)
+	}
+	scheduled, err := s.ListScheduledChirps(ctx, walt.ID)
+	if err != nil || len(scheduled) != 2 || scheduled[0].ID != due.ID || scheduled[1].ID != later.ID {
+		t.Fatalf("expected the scheduled chirps in publishing order, got %+v, %v", scheduled, err)
+	}
+	if n, err := s.CountScheduledChirps(ctx, walt.ID); err != nil || n != 2 {
+		t.Errorf("expected 2 scheduled chirps, got %d, %v", n, err)
+	}
+	between := database.CountScheduledChirpsBetweenParams{
+		UserID: walt.ID,
+		After:  later.PublishAt.Add(-time.Hour),
+		Before: later.PublishAt.Add(time.Hour),
+	}
+	if n, err := s.CountScheduledChirpsBetween(ctx, between); err != nil || n != 1 {
+		t.Errorf("expected 1 chirp scheduled around %v, got %d, %v", later.PublishAt, n, err)
+	}
+	between.After = later.PublishAt
+	if n, err := s.CountScheduledChirpsBetween(ctx, between); err != nil || n != 0 {
+		t.Errorf("expected the window to exclude its ends, got %d, %v", n, err)
+	}
+
+	published, err := s.PublishDueChirps(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if len(published) != 1 || published[0].ID != due.ID || published[0].ThreadID != due.ID || published[0].Body != "due" || published[0].UserID.UUID != walt.ID {
+		t.Fatalf("expected only the due chirp to be published, got %+v", published)
+	}
+	if _, err := s.GetChirpByID(ctx, due.ID); err != nil {
+		t.Errorf("expected the published chirp to be readable, got %v", err)
+	}
+	if published, err := s.PublishDueChirps(ctx); err != nil || len(published) != 0 {
+		t.Errorf("expected nothing left to publish, got %+v, %v", published, err)
+	}
+	if n, err := s.CountScheduledChirps(ctx, walt.ID); err != nil || n != 1 {
+		t.Errorf("expected 1 scheduled chirp left, got %d, %v", n, err)
+	}
+
+	author := uuid.NullUUID{UUID: walt.ID, Valid: true}
+	since := published[0].CreatedAt.Add(-time.Second)
+	if n, err := s.CountChirpsSince(ctx, database.CountChirpsSinceParams{UserID: author, Since: since}); err != nil || n != 1 {
+		t.Errorf("expected 1 recent chirp, got %d, %v", n, err)
+	}
+	if n, err := s.CountChirpsSince(ctx, database.CountChirpsSinceParams{UserID: author, Since: published[0].CreatedAt}); err != nil || n != 0 {
+		t.Errorf("expected no chirps after the last one, got %d, %v", n, err)
+	}
+}
+
+func testPasswordReset(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	walt := mustCreateUser(t, s, "walt@breakingbad.com")
+	jesse := mustCreateUser(t, s, "jesse@breakingbad.com")
+	issue := func(hash string, userID uuid.UUID, expiresAt time.Time) {
+		t.Helper()
+		if err := s.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{TokenHash: hash, UserID: userID, ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("failed to create reset token: %v", err)
+		}
+	}
+	future := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue("a", walt.ID, future)
+	issue("b", walt.ID, future)
+	issue("expired", walt.ID, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	issue("jesse", jesse.ID, future)
+	for _, token := range []struct {
+		hash   string
+		userID uuid.UUID
+	}{{"walt-session", walt.ID}, {"jesse-session", jesse.ID}} {
+		if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			TokenHash: token.hash,
+			UserID:    uuid.NullUUID{UUID: token.userID, Valid: true},
+			FamilyID:  uuid.New(),
+		}); err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+	}
+
+	if _, err := s.ResetPassword(ctx, database.ResetPasswordParams{TokenHash: "expired", HashedPassword: "new"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected an expired token to be refused, got %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, database.ResetPasswordParams{TokenHash: "unknown", HashedPassword: "new"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected an unknown token to be refused, got %v", err)
+	}
+
+	userID, err := s.ResetPassword(ctx, database.ResetPasswordParams{TokenHash: "a", HashedPassword: "new"})
+	if err != nil || userID != walt.ID {
+		t.Fatalf("expected to reset walt's password, got %v, %v", userID, err)
+	}
+	if user, _ := s.GetUserByID(ctx, walt.ID); user.HashedPassword != "new" {
+		t.Errorf("expected the new password to be stored, got %q", user.HashedPassword)
+	}
+	for _, hash := range []string{"a", "b"} {
+		if _, err := s.ResetPassword(ctx, database.ResetPasswordParams{TokenHash: hash, HashedPassword: "again"}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected token %q to be used up, got %v", hash, err)
+		}
+	}
+	if token, _ := s.GetRefreshToken(ctx, "walt-session"); !token.RevokedAt.Valid {
+		t.Error("expected walt's sessions to be revoked")
+	}
+
+	if token, _ := s.GetRefreshToken(ctx, "jesse-session"); token.RevokedAt.Valid {
+		t.Error("expected jesse's sessions to be left alone")
+	}
+	if user, _ := s.GetUserByID(ctx, jesse.ID); user.HashedPassword != "x" {
+		t.Errorf("expected jesse's password to be unchanged, got %q", user.HashedPassword)
+	}
+	if _, err := s.ResetPassword(ctx, database.ResetPasswordParams{TokenHash: "jesse", HashedPassword: "new"}); err != nil {
+		t.Errorf("expected jesse's token to still work, got %v", err)
+	}
+}
+
+func testDeleteAllUsers(t *testing.T, s database.Querier) {
+	ctx := context.Background()
+	user := mustCreateUser(t, s, "walt@breakingbad.com")
+	chirp := mustCreateChirp(t, s, user.ID, "Say my name")
+	if _, err := s.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: "a",
+		UserID:    uuid.NullUUID{UUID: user.ID, Valid: true},
+		FamilyID:  uuid.New(),
+	}); err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	if err := s.DeleteAllUsers(ctx); err != nil {
+		t.Fatalf("failed to delete users: %v", err)
+	}
+	if _, err := s.GetUserByID(ctx, user.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected the user to be gone, got %v", err)
+	}
+	if _, err := s.GetChirpThreadID(ctx, chirp.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected the cascade to remove the chirp, got %v", err)
+	}
+	if _, err := s.GetRefreshToken(ctx, "a"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected the cascade to remove the token, got %v", err)
+	}
+	if rows, _ := s.SearchChirps(ctx, database.SearchChirpsParams{Query: "name", PageSize: 10}); len(rows) != 0 {
+		t.Errorf("expected the search index to drop the chirp, got %+v", rows)
+	}
+}
@@ -2,16 +2,18 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
-	if err != nil {
-		log.Println(err)
-	}
+	// The request ID has already been set on the response, which saves
+	// threading the request's context through every call site
+	logger := slog.With(slog.String("request_id", w.Header().Get(requestIDHeader)))
 	if code > 499 {
-		log.Printf("Responding with 5XX error: %s", msg)
+		logger.Error("Responding with 5XX error", slog.String("response", msg), slog.Any("error", err))
+	} else if err != nil {
+		logger.Info("Request failed", slog.Int("status", code), slog.String("response", msg), slog.Any("error", err))
 	}
 	type errorResponse struct {
 		Error string `json:"error"`
@@ -25,7 +27,7 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	dat, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", slog.Any("error", err))
 		w.WriteHeader(500)
 		return
 	}
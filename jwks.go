@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/config"
+)
+
+// loadJWTKeys builds the key set access tokens are signed and verified
+// with. SigningKeyFile is a PEM Ed25519 or RSA private key; when it's empty
+// tokens are signed with HS256 using Secret. VerificationKeyFiles are PEM
+// public keys that are still accepted, e.g. the previous signing key during
+// a rotation.
+func loadJWTKeys(cfg config.JWTConfig) (*auth.KeySet, error) {
+	keys := auth.NewKeySet(cfg.Secret)
+
+	if signingKeyFile := cfg.SigningKeyFile; signingKeyFile != "" {
+		dat, err := os.ReadFile(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := keys.SetSigningKey(dat); err != nil {
+			return nil, fmt.Errorf("%s: %w", signingKeyFile, err)
+		}
+	} else if cfg.Secret == "" {
+		return nil, fmt.Errorf("either secret or JWT_SIGNING_KEY_FILE must be set")
+	}
+
+	for _, path := range cfg.VerificationKeyFiles {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := keys.AddVerificationKey(dat); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return keys, nil
+}
+
+// handle function for /.well-known/jwks.json endpoint
+func (cfg *apiConfig) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handleLikeChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, true)
+}
+
+func (cfg *apiConfig) handleUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, false)
+}
+
+// setChirpLike likes or unlikes {chirpID} for the caller and responds with
+// the chirp's updated count. Both directions are idempotent.
+func (cfg *apiConfig) setChirpLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse chirpID", err)
+		return
+	}
+
+
+	userID := requestUserID(r)
+
+
+	if _, err := cfg.db.GetChirpByID(r.Context(), chirpID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		return
+	}
+
+
+	if liked {
+		err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+			ChirpID: chirpID,
+			UserID:  userID,
+		})
+	} else {
+		err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+			ChirpID: chirpID,
+			UserID:  userID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update like", err)
+		return
+	}
+
+
+	// Read the chirp back so the count includes concurrent likes
+	chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		return
+	}
+
+	response := chirpFromDB(chirp)
+	response.LikedByMe = &liked
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+
+// markLikedByMe sets liked_by_me on each chirp for the viewer. Chirps are
+// left untouched for anonymous requests.
+func (cfg *apiConfig) markLikedByMe(ctx context.Context, chirps []Chirp, viewer uuid.NullUUID) error {
+	if !viewer.Valid || len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	likedIDs, err := cfg.db.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+		UserID:   viewer.UUID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+	for i := range chirps {
+		likedByMe := liked[chirps[i].ID]
+		chirps[i].LikedByMe = &likedByMe
+	}
+	return nil
+}
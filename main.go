@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
 	"net/http"
-	"log"
+	"log/slog"
+	"sync"
 	"sync/atomic"
+    "github.com/NachoGz/chirpy/internal/auth"
+    "github.com/NachoGz/chirpy/internal/config"
     "github.com/NachoGz/chirpy/internal/database"
+    "github.com/NachoGz/chirpy/internal/logging"
+    "github.com/NachoGz/chirpy/internal/mailer"
+    "github.com/NachoGz/chirpy/internal/metrics"
+    "github.com/NachoGz/chirpy/internal/moderation"
     _ "github.com/lib/pq" // PostgreSQL driver
     "github.com/joho/godotenv" // For loading .env files
     "database/sql"
     "os"
+	"os/signal"
+	"syscall"
 	"time"
 	"github.com/google/uuid"
 )
@@ -16,9 +26,18 @@ import (
 
 type apiConfig struct {
 	fileserverHits  atomic.Int32
-    db         		*database.Queries
-	secret			string
-	PolkaKey		string
+    db         		database.Querier
+	dbConn			*sql.DB
+	backend			backend
+	jwtKeys			*auth.KeySet
+	config			config.Config
+	moderator		*moderation.Moderator
+	metrics			*metrics.Metrics
+	mailer			mailer.Mailer
+	// background tracks work handlers hand off to finish after responding
+	background		sync.WaitGroup
+	// chirpLocks serializes a user's chirp quota checks with their inserts
+	chirpLocks		userLocks
 
 }
 
@@ -36,68 +55,157 @@ type Chirp struct {
 	UpdatedAt 	time.Time `json:"updated_at"`
 	Body  	  	string    `json:"body"`
 	UserID	  	uuid.UUID `json:"user_id"`
+	LikeCount	int32	  `json:"like_count"`
+	LikedByMe	*bool	  `json:"liked_by_me,omitempty"`
+	InReplyToID	uuid.NullUUID `json:"in_reply_to_id"`
+	ThreadID	uuid.UUID `json:"thread_id"`
+	Edited		bool	  `json:"edited"`
 }
 
 
 
 func main() {
-    const filepathRoot = "."
-	const port = "8080"
-
     godotenv.Load()
-    dbURL := os.Getenv("DB_URL")
-    if dbURL == "" {
-		log.Fatal("DB_URL must be set")
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stdout, slog.LevelInfo)))
+
+	cfg, args, err := config.Parse(os.Args[1:], os.Getenv)
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	if len(args) > 0 && args[0] == "migrate" {
+		runMigrate(cfg, args[1:])
+		return
+	} else if len(args) > 0 {
+		fatal("Unknown command "+args[0], nil)
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", err)
+	}
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stdout, logging.ParseLevel(cfg.LogLevel))))
+	if len(cfg.Polka.Secrets) == 0 {
+		slog.Warn("No Polka webhook secrets are set; every webhook will be refused")
 	}
     
-    dbConn, err := sql.Open("postgres", dbURL)
+    dbConn, backend, err := openDatabase(cfg.DBURL)
     if err != nil {
-        log.Fatalf("Could not connect to database: %v", err)
+        fatal("Could not connect to database", err)
     }
-    defer dbConn.Close()
     
-    dbQueries := database.New(dbConn)
-    secret := os.Getenv("secret")
-    PolkaKey := os.Getenv("POLKA_KEY")
+    dbQueries := backend.queries(dbConn)
+
+	// SIGINT or SIGTERM starts a graceful shutdown; a second one kills the
+	// process straight away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if cfg.MigrateOnStart {
+		if err := migrateUp(ctx, dbConn, backend); err != nil {
+			fatal("Could not apply migrations", err)
+		}
+	}
+
+	jwtKeys, err := loadJWTKeys(cfg.JWT)
+	if err != nil {
+		fatal("Could not load JWT keys", err)
+	}
+
+	// Chirps are moderated with the word list file (or the built-in words)
+	// plus whatever rules admins have added to the database
+	var wordList moderation.Source = moderation.WordList(moderation.DefaultWords)
+	if cfg.ModerationRulesFile != "" {
+		wordList = moderation.FileSource(cfg.ModerationRulesFile)
+	}
+	moderator := moderation.New(wordList, dbRuleSource{db: dbQueries})
+	if err := moderator.Reload(ctx); err != nil {
+		fatal("Could not load moderation rules", err)
+	}
+
+	// The jobs run until ctx is done and are waited for before the
+	// database is closed, so none of them is left mid-query
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		moderator.Watch(ctx, time.Minute)
+	}()
 
 	apiCfg := apiConfig{
         fileserverHits: atomic.Int32{},
         db: 			dbQueries,
-		secret:			secret,
-		PolkaKey:		PolkaKey,
+		dbConn:			dbConn,
+		backend:		backend,
+		jwtKeys:		jwtKeys,
+		config:			cfg,
+		moderator:		moderator,
+		metrics:		metrics.New(dbConn),
+		mailer:			newMailer(cfg),
 	}
+	if apiCfg.mailer == nil {
+		slog.Warn("No SMTP host or mail directory is set; password reset is disabled")
+	}
+	jobs.Add(2)
+	go func() {
+		defer jobs.Done()
+		apiCfg.expireSubscriptions(ctx, cfg.Subscriptions.CheckInterval)
+	}()
+	go func() {
+		defer jobs.Done()
+		apiCfg.publishScheduledChirps(ctx, cfg.PublishInterval)
+	}()
 
-	mux := http.NewServeMux()
-	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
-	mux.Handle("/app/", fsHandler)
-	
-	// endpoints
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handleMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handleReset)
-
-	mux.HandleFunc("GET /api/healthz", handleReadiness)
 
-    mux.HandleFunc("POST /api/chirps", apiCfg.handleCreateChirp)
-    mux.HandleFunc("GET /api/chirps", apiCfg.handleGetChirps)
-    mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handleGetChirpByID)
-    mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handleDeleteChirp)
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+		Handler:           apiCfg.routes(),
+	}
 	
-	mux.HandleFunc("POST /api/users", apiCfg.handleCreateUser)
-	mux.HandleFunc("PUT /api/users", apiCfg.handleUpdateUserInfo)
-	mux.HandleFunc("POST /api/login", apiCfg.handleLogin)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handleRefreshToken)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handleRevokeToken)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handleUpgradedToChirpyRed)
+	slog.Info("Serving files", slog.String("root", cfg.FilepathRoot), slog.String("port", cfg.Port))
+	// serve only returns once handlers have finished. Then the jobs are
+	// stopped and they and the work handlers left running in the background
+	// are waited for, so closing the database can't pull it out from under
+	// them
+	err = serve(ctx, server, cfg.Server.ShutdownTimeout)
+	stop()
+	jobs.Wait()
+	apiCfg.background.Wait()
+	dbConn.Close()
+	if err != nil {
+		fatal("Server stopped", err)
+	}
+	slog.Info("Server stopped")
+}
 
 
+// fatal logs msg and err and exits.
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, slog.Any("error", err))
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
+}
 
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+
+// runMigrate handles `chirpy migrate ...`, which only needs the database.
+func runMigrate(cfg config.Config, args []string) {
+	if cfg.DBURL == "" {
+		fatal("DB_URL must be set", nil)
 	}
-	
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(server.ListenAndServe())
+	dbConn, backend, err := openDatabase(cfg.DBURL)
+	if err != nil {
+		fatal("Could not connect to database", err)
+	}
+	defer dbConn.Close()
 
-	return 
-}
\ No newline at end of file
+	if err := runMigrateCommand(context.Background(), dbConn, backend, args, os.Stdout); err != nil {
+		dbConn.Close()
+		fatal("Migration failed", err)
+	}
+}
@@ -22,6 +22,16 @@ func (cfg *apiConfig) handleMetrics(w http.ResponseWriter, r *http.Request) {
 }
 
 
+// handlePrometheusMetrics serves the Prometheus metrics. Route names and
+// traffic are nothing to hand out publicly, so scrapers need the admin key.
+func (cfg *apiConfig) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	cfg.metrics.Handler().ServeHTTP(w, r)
+}
+
+
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverHits.Add(1)
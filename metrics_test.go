@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	chirp := postChirp(t, server, user, "Say my name")
+
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/"+chirp.ID.String(), "", nil, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	for _, credential := range []string{"", user.Token, "ApiKey wrong-key"} {
+		if res := doJSON(t, "GET", server.URL+"/metrics", credential, nil, nil); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 scraping with %q, got %d", credential, res.StatusCode)
+		}
+	}
+
+	req, err := http.NewRequest("GET", server.URL+"/metrics", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "ApiKey "+testAdminKey)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 scraping with the admin key, got %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	metrics := string(body)
+
+	// Requests are labelled by the route they matched, not the path, so a
+	// chirp ID never becomes a label value
+	want := `chirpy_http_requests_total{code="200",method="GET",route="GET /api/chirps/{chirpID}"} 1`
+	if !strings.Contains(metrics, want) {
+		t.Errorf("expected %s in the scrape, got:\n%s", want, metrics)
+	}
+	if !strings.Contains(metrics, `chirpy_http_requests_total{code="201",method="POST",route="POST /api/chirps"} 1`) {
+		t.Error("expected the chirp creation to be counted")
+	}
+	if strings.Contains(metrics, chirp.ID.String()) {
+		t.Error("expected no raw paths in the labels")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	cfg := &apiConfig{jwtKeys: auth.NewKeySet("test-secret")}
+	userID := uuid.New()
+	valid, err := cfg.jwtKeys.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	expired, err := cfg.jwtKeys.MakeJWT(userID, -time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	otherKey, err := auth.NewKeySet("other-secret").MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+
+	// The handlers report who they think the caller is
+	whoami := func(w http.ResponseWriter, r *http.Request) {
+		viewer := viewerID(r)
+		if !viewer.Valid {
+			w.Write([]byte("anonymous"))
+			return
+		}
+		w.Write([]byte(viewer.UUID.String()))
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /private", requireAuth(whoami))
+	mux.Handle("GET /public", optionalAuth(whoami))
+	mux.HandleFunc("GET /unwrapped", whoami)
+	handler := cfg.middlewareAuthenticate(mux)
+
+	cases := []struct {
+		name          string
+		path          string
+		authorization string
+		code          int
+		body          string
+		challenge     string
+	}{
+		{"required with a token", "/private", "Bearer " + valid, http.StatusOK, userID.String(), ""},
+		{"required without credentials", "/private", "", http.StatusUnauthorized, "", `Bearer realm="chirpy"`},
+		{"required with another scheme", "/private", "ApiKey " + valid, http.StatusUnauthorized, "", `Bearer realm="chirpy"`},
+		{"required with garbage", "/private", "Bearer not-a-jwt", http.StatusUnauthorized, "", `Bearer realm="chirpy", error="invalid_token"`},
+		{"required with an expired token", "/private", "Bearer " + expired, http.StatusUnauthorized, "", `Bearer realm="chirpy", error="invalid_token"`},
+		{"required with a foreign token", "/private", "Bearer " + otherKey, http.StatusUnauthorized, "", `Bearer realm="chirpy", error="invalid_token"`},
+		{"optional with a token", "/public", "Bearer " + valid, http.StatusOK, userID.String(), ""},
+		{"optional without credentials", "/public", "", http.StatusOK, "anonymous", ""},
+		{"optional with garbage", "/public", "Bearer not-a-jwt", http.StatusOK, "anonymous", ""},
+		{"optional with an expired token", "/public", "Bearer " + expired, http.StatusOK, "anonymous", ""},
+		{"unwrapped with garbage", "/unwrapped", "Bearer not-a-jwt", http.StatusOK, "anonymous", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", c.path, nil)
+			if c.authorization != "" {
+				req.Header.Set("Authorization", c.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Fatalf("expected %d, got %d", c.code, rec.Code)
+			}
+			if c.body != "" && rec.Body.String() != c.body {
+				t.Errorf("expected the caller to be %s, got %s", c.body, rec.Body.String())
+			}
+			challenge := rec.Header().Get("WWW-Authenticate")
+			if c.challenge == "" && challenge != "" {
+				t.Errorf("expected no challenge, got %q", challenge)
+			}
+			if c.challenge != "" && !strings.HasPrefix(challenge, c.challenge) {
+				t.Errorf("expected a challenge starting %q, got %q", c.challenge, challenge)
+			}
+			// Without credentials there is no error to report (RFC 6750 section 3.1)
+			if c.challenge == `Bearer realm="chirpy"` && challenge != c.challenge {
+				t.Errorf("expected a bare challenge, got %q", challenge)
+			}
+		})
+	}
+}
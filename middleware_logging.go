@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/logging"
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limits the IDs we accept from clients, so a caller can't
+// stuff arbitrary text into our logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// middlewareRequestID gives every request an ID, reusing the caller's
+// X-Request-ID when it has a sane one. The ID is echoed in the response and
+// attached to every log line written with the request's context.
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// middlewareAccessLog writes one line per request. It has to sit inside
+// middlewareAuthenticate to see the caller, and directly around the mux
+// (or other middleware that passes the request through unchanged) to see
+// the matched route.
+func middlewareAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		attrs := []any{
+			slog.String("method", r.Method),
+			slog.String("route", routeLabel(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if userID, ok := auth.UserIDFromContext(r.Context()); ok {
+			attrs = append(attrs, slog.String("user_id", userID.String()))
+		}
+		slog.InfoContext(r.Context(), "request", attrs...)
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// migrationFS holds the goose migrations for PostgreSQL, so a binary always
+// knows which schema it was built against. The SQLite ones live with
+// sqlitestore.
+//
+//go:embed sql/schema/*.sql
+var migrationFS embed.FS
+
+// latestMigration returns the version of the newest migration for b,
+// taken from the numeric prefix of its file name.
+func latestMigration(b backend) (int64, error) {
+	files, err := fs.Glob(b.migrations, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(path.Base(file), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// appliedMigration returns the newest migration goose has recorded as
+// applied to the database.
+func appliedMigration(ctx context.Context, db *sql.DB) (int64, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx,
+		"SELECT MAX(version_id) FROM goose_db_version WHERE is_applied").Scan(&version)
+	return version.Int64, err
+}
+
+// newMigrator returns a goose provider for b's embedded migrations. On
+// Postgres every operation holds an advisory lock for its duration, so
+// replicas migrating on start at the same time take turns instead of
+// racing.
+func newMigrator(db *sql.DB, b backend) (*goose.Provider, error) {
+	var options []goose.ProviderOption
+	if b.newLocker != nil {
+		locker, err := b.newLocker()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, goose.WithSessionLocker(locker))
+	}
+	return goose.NewProvider(b.dialect, db, b.migrations, options...)
+}
+
+// migrateUp applies every pending migration.
+func migrateUp(ctx context.Context, db *sql.DB, b backend) error {
+	migrator, err := newMigrator(db, b)
+	if err != nil {
+		return err
+	}
+	results, err := migrator.Up(ctx)
+	for _, result := range results {
+		slog.InfoContext(ctx, "Applied migration",
+			slog.Int64("version", result.Source.Version),
+			slog.Duration("duration", result.Duration))
+	}
+	return err
+}
+
+// runMigrateCommand implements `chirpy migrate up|down|status|version`,
+// writing its report to out.
+func runMigrateCommand(ctx context.Context, db *sql.DB, b backend, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: chirpy [flags] migrate up|down|status|version")
+	}
+
+	migrator, err := newMigrator(db, b)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			fmt.Fprintln(out, result)
+		}
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if result != nil {
+			fmt.Fprintln(out, result)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%-8s %-26s %s\n", status.State, appliedAt, status.Source.Path)
+		}
+		return nil
+	case "version":
+		current, target, err := migrator.GetVersions(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "current: %d\nlatest:  %d\n", current, target)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
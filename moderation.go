@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/NachoGz/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+type ModerationRule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+}
+
+// dbRuleSource feeds the moderation_rules table into the moderator.
+type dbRuleSource struct {
+	db database.Querier
+}
+
+func (s dbRuleSource) Rules(ctx context.Context) ([]moderation.Rule, error) {
+	rows, err := s.db.ListModerationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]moderation.Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, moderation.Rule{
+			Kind:    moderation.Kind(row.Kind),
+			Pattern: row.Pattern,
+			Action:  moderation.Action(row.Action),
+		})
+	}
+	return rules, nil
+}
+
+
+// requireAdmin checks the "ApiKey" authorization header against
+// ADMIN_API_KEY. Admin endpoints are disabled when no key is configured.
+func (cfg *apiConfig) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	api_key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "ApiKey not found", err)
+		return false
+	}
+	adminKey := cfg.config.AdminAPIKey
+	if adminKey == "" || subtle.ConstantTimeCompare([]byte(adminKey), []byte(api_key)) != 1 {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect ApiKey", nil)
+		return false
+	}
+	return true
+}
+
+
+func (cfg *apiConfig) handleListModerationRules(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	rows, err := cfg.db.ListModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation rules", err)
+		return
+	}
+
+	rules := []ModerationRule{}
+	for _, row := range rows {
+		rules = append(rules, ModerationRule(row))
+	}
+	respondWithJSON(w, http.StatusOK, rules)
+}
+
+
+func (cfg *apiConfig) handleCreateModerationRule(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Kind    string `json:"kind"`
+		Pattern string `json:"pattern"`
+		Action  string `json:"action"`
+	}
+
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+
+	rule := moderation.Rule{
+		Kind:    moderation.Kind(params.Kind),
+		Pattern: params.Pattern,
+		Action:  moderation.Action(params.Action),
+	}
+	if err := rule.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid moderation rule: "+err.Error(), err)
+		return
+	}
+
+
+	row, err := cfg.db.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Kind:    params.Kind,
+		Pattern: params.Pattern,
+		Action:  params.Action,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "A "+params.Kind+" rule for that pattern already exists", nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create moderation rule", err)
+		return
+	}
+
+
+	if err := cfg.moderator.Reload(r.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation rules", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, ModerationRule(row))
+}
+
+
+func (cfg *apiConfig) handleDeleteModerationRule(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse ruleID", err)
+		return
+	}
+
+
+	deleted, err := cfg.db.DeleteModerationRule(r.Context(), ruleID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete moderation rule", err)
+		return
+	} else if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Moderation rule not found", nil)
+		return
+	}
+
+
+	if err := cfg.moderator.Reload(r.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation rules", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+// handleReloadModeration re-reads the word list file and the database rules,
+// e.g. after editing the file.
+func (cfg *apiConfig) handleReloadModeration(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+
+	if err := cfg.moderator.Reload(r.Context()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation rules", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
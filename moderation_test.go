@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreateModerationRule(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	url := server.URL + "/admin/moderation/rules"
+	admin := "ApiKey " + testAdminKey
+	rule := map[string]string{"kind": "word", "pattern": "heisenberg", "action": "mask"}
+
+	if res := doJSON(t, "POST", url, "", rule, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the admin key, got %d", res.StatusCode)
+	}
+
+	var created ModerationRule
+	if res := doJSON(t, "POST", url, admin, rule, &created); res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.StatusCode)
+	}
+	if created.Kind != "word" || created.Pattern != "heisenberg" || created.Action != "mask" {
+		t.Errorf("unexpected rule %+v", created)
+	}
+	if chirp := postChirp(t, server, walt, "Call me Heisenberg"); chirp.Body != "Call me ****" {
+		t.Errorf("expected the new rule to apply straight away, got %q", chirp.Body)
+	}
+
+	// The same pattern again is a conflict, whatever the action
+	rule["action"] = "reject"
+	if res := doJSON(t, "POST", url, admin, rule, nil); res.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate rule, got %d", res.StatusCode)
+	}
+
+	for _, bad := range []map[string]string{
+		{"kind": "word", "pattern": "say my name", "action": "mask"},
+		{"kind": "word", "pattern": "!!!", "action": "mask"},
+		{"kind": "regex", "pattern": "(", "action": "mask"},
+		{"kind": "glob", "pattern": "*", "action": "mask"},
+		{"kind": "word", "pattern": "blue", "action": "delete"},
+	} {
+		if res := doJSON(t, "POST", url, admin, bad, nil); res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", bad, res.StatusCode)
+		}
+	}
+
+	var rules []ModerationRule
+	doJSON(t, "GET", url, admin, nil, &rules)
+	if len(rules) != 1 || rules[0].ID != created.ID {
+		t.Errorf("expected only the first rule, got %+v", rules)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// pageCursor marks a position in a list ordered by (created_at, id).
+// Backward cursors ask for the page that comes before the position.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// offsetCursor marks a position in a ranked list, which has no stable
+// (created_at, id) order and so is paged by offset.
+type offsetCursor struct {
+	Offset int `json:"o"`
+}
+
+type pageRequest struct {
+	Limit  int32
+	Cursor *pageCursor
+}
+
+func (c pageCursor) encode() string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func (c offsetCursor) encode() string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeOffsetCursor(s string) (offsetCursor, error) {
+	var c offsetCursor
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("malformed cursor")
+	}
+	if err := json.Unmarshal(dat, &c); err != nil || c.Offset < 0 || c.Offset > math.MaxInt32 {
+		return c, errors.New("malformed cursor")
+	}
+	return c, nil
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("malformed cursor")
+	}
+	if err := json.Unmarshal(dat, &c); err != nil || c.CreatedAt.IsZero() {
+		return c, errors.New("malformed cursor")
+	}
+	return c, nil
+}
+
+// parsePageLimit reads the limit query parameter.
+func parsePageLimit(r *http.Request) (int32, error) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return int32(min(limit, maxPageSize)), nil
+}
+
+// parsePageRequest reads the limit and cursor query parameters.
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	page := pageRequest{Limit: defaultPageSize}
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limit
+
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		cursor, err := decodeCursor(cursorStr)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = &cursor
+	}
+
+	return page, nil
+}
+
+// backward reports whether the page was requested with a prev cursor.
+func (p pageRequest) backward() bool {
+	return p.Cursor != nil && p.Cursor.Backward
+}
+
+// cursorParams converts the cursor into the nullable query arguments the
+// List*Before / List*After queries expect.
+func (p pageRequest) cursorParams() (sql.NullTime, uuid.NullUUID) {
+	if p.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+// trimPage drops the extra look-ahead row and puts the rows in display
+// order, reporting whether there were more rows than the page size.
+func trimPage[T any](p pageRequest, rows []T) ([]T, bool) {
+	hasMore := len(rows) > int(p.Limit)
+	if hasMore {
+		rows = rows[:p.Limit]
+	}
+	if p.backward() {
+		slices.Reverse(rows)
+	}
+	return rows, hasMore
+}
+
+// setPageLinks advertises the neighbouring pages through the Link header
+// and the X-Next-Cursor / X-Prev-Cursor headers, alongside the cursors in
+// the body. next and prev are encoded cursors; nil means there is no page
+// in that direction.
+func setPageLinks(w http.ResponseWriter, r *http.Request, next, prev *string) {
+	var links []string
+	if next != nil {
+		w.Header().Set("X-Next-Cursor", *next)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(r, *next)))
+	}
+	if prev != nil {
+		w.Header().Set("X-Prev-Cursor", *prev)
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(r, *prev)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// cursorString encodes c for the response, where a missing page is nil.
+func cursorString(c *pageCursor) *string {
+	if c == nil {
+		return nil
+	}
+	s := c.encode()
+	return &s
+}
+
+func pageURL(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", cursor)
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// pageBounds works out the cursors around a fetched page. items must
+// already be trimmed to the page size and in display order; hasMore reports
+// whether the query returned more rows than the page size.
+func pageBounds[T any](page pageRequest, items []T, hasMore bool, position func(T) pageCursor) (next, prev *pageCursor) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	hasNext := hasMore
+	hasPrev := page.Cursor != nil
+	if page.backward() {
+		hasNext = true
+		hasPrev = hasMore
+	}
+
+	if hasNext {
+		c := position(items[len(items)-1])
+		next = &c
+	}
+	if hasPrev {
+		c := position(items[0])
+		c.Backward = true
+		prev = &c
+	}
+	return next, prev
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/NachoGz/chirpy/internal/mailer"
+)
+
+// newMailer picks how email goes out: SMTP when a host is configured, a
+// directory of message files when one is set, and otherwise the log on the
+// dev platform. In prod with neither it returns nil and nothing that needs
+// email works; logging mail there would put live reset links in the logs.
+func newMailer(cfg config.Config) mailer.Mailer {
+	switch {
+	case cfg.Mail.SMTP.Host != "":
+		return mailer.SMTP{
+			Host:		cfg.Mail.SMTP.Host,
+			Port:		cfg.Mail.SMTP.Port,
+			Username:	cfg.Mail.SMTP.Username,
+			Password:	cfg.Mail.SMTP.Password,
+			From:		cfg.Mail.From,
+		}
+	case cfg.Mail.Dir != "":
+		return mailer.Dir{Path: cfg.Mail.Dir, From: cfg.Mail.From}
+	case cfg.Platform == "dev":
+		return mailer.Log{Logger: slog.Default()}
+	default:
+		return nil
+	}
+}
+
+
+// handleRequestPasswordReset emails a reset link to the address if it
+// belongs to a user. It answers 202 either way, and the token and email
+// are made in the background, so neither the status nor the response time
+// tells the caller who has an account.
+func (cfg *apiConfig) handleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	if cfg.mailer == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Password reset is not configured", nil)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "Email is required", nil)
+		return
+	}
+
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't look up user", err)
+		return
+	}
+
+	// The request's values (its ID for the logs) carry over, but not its
+	// cancellation, since the response goes out first
+	ctx := context.WithoutCancel(r.Context())
+	cfg.background.Add(1)
+	go func() {
+		defer cfg.background.Done()
+		ctx, cancel := context.WithTimeout(ctx, cfg.config.Mail.SendTimeout)
+		defer cancel()
+		if err := cfg.sendPasswordReset(ctx, user); err != nil {
+			slog.ErrorContext(ctx, "Couldn't send password reset email", slog.String("user_id", user.ID.String()), slog.Any("error", err))
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+
+// sendPasswordReset issues a reset token for user and emails them the
+// link. Only the token's digest is stored, like refresh tokens, so a leaked
+// table can't be used to take over accounts.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, user database.User) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().UTC().Add(cfg.config.PasswordReset.TokenTTL)
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash:	auth.HashRefreshToken(token),
+		UserID:		user.ID,
+		ExpiresAt:	expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("creating reset token: %w", err)
+	}
+
+	link, err := resetLink(cfg.config.PasswordReset.LinkURL, token)
+	if err != nil {
+		return fmt.Errorf("building reset link: %w", err)
+	}
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:			user.Email,
+		Subject:	"Reset your Chirpy password",
+		Body:		fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n" +
+			"Follow this link to choose a new one:\n\n%s\n\n" +
+			"The link works once and expires at %s. If you didn't ask for this, you can ignore this email.\n",
+			link, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+
+// resetLink adds token to the configured link URL as a query parameter,
+// keeping any query the URL already has.
+func resetLink(linkURL, token string) (string, error) {
+	u, err := url.Parse(linkURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+
+// handleConfirmPasswordReset sets a new password with a token from a reset
+// email. The token and any others the user was sent stop working, and all
+// of the user's refresh tokens are revoked so other sessions are signed out.
+func (cfg *apiConfig) handleConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Token == "" || params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Token and password are required", nil)
+		return
+	}
+
+
+	hashed_passwd, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+		return
+	}
+
+	_, err = cfg.db.ResetPassword(r.Context(), database.ResetPasswordParams{
+		TokenHash:		auth.HashRefreshToken(params.Token),
+		HashedPassword:	hashed_passwd,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token", err)
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reset password", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/config"
+)
+
+var resetTokenPattern = regexp.MustCompile(`token=([0-9a-f]+)`)
+
+// readResetToken waits for the reset email, which is sent in the
+// background, and returns the token from it.
+func readResetToken(t *testing.T, dir string) string {
+	t.Helper()
+
+	var emails []string
+	for deadline := time.Now().Add(5 * time.Second); len(emails) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		emails, _ = filepath.Glob(filepath.Join(dir, "*.eml"))
+	}
+	if len(emails) != 1 {
+		t.Fatalf("expected one email, got %v", emails)
+	}
+	data, err := os.ReadFile(emails[0])
+	if err != nil {
+		t.Fatalf("failed to read email: %v", err)
+	}
+	match := resetTokenPattern.FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("expected a reset link in the email, got %s", data)
+	}
+	return string(match[1])
+}
+
+func TestPasswordReset(t *testing.T) {
+	mailDir := t.TempDir()
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Mail.Dir = mailDir
+	})
+	walt := signUp(t, server, "walt@breakingbad.com")
+
+	if res := doJSON(t, "POST", server.URL+"/api/password-reset/request", "", map[string]string{"email": "saul@breakingbad.com"}, nil); res.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202 for an unknown email, got %d", res.StatusCode)
+	}
+	if entries, _ := os.ReadDir(mailDir); len(entries) != 0 {
+		t.Fatalf("expected no email for an unknown address, got %d", len(entries))
+	}
+
+	if res := doJSON(t, "POST", server.URL+"/api/password-reset/request", "", map[string]string{"email": "walt@breakingbad.com"}, nil); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 requesting a reset, got %d", res.StatusCode)
+	}
+	token := readResetToken(t, mailDir)
+
+	confirm := map[string]string{"token": token, "password": "heisenberg"}
+	if res := doJSON(t, "POST", server.URL+"/api/password-reset/confirm", "", map[string]string{"token": "deadbeef", "password": "heisenberg"}, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a made-up token, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/password-reset/confirm", "", confirm, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 resetting the password, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/password-reset/confirm", "", confirm, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 reusing the token, got %d", res.StatusCode)
+	}
+
+	old := map[string]string{"email": "walt@breakingbad.com", "password": testPassword}
+	if res := doJSON(t, "POST", server.URL+"/api/login", "", old, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with the old password, got %d", res.StatusCode)
+	}
+	updated := map[string]string{"email": "walt@breakingbad.com", "password": "heisenberg"}
+	if res := doJSON(t, "POST", server.URL+"/api/login", "", updated, nil); res.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with the new password, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/refresh", walt.RefreshToken, nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the old session to be signed out, got %d", res.StatusCode)
+	}
+}
+
+func TestPasswordResetNeedsMailerInProd(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Platform = "prod"
+	})
+	signUp(t, server, "walt@breakingbad.com")
+
+	if res := doJSON(t, "POST", server.URL+"/api/password-reset/request", "", map[string]string{"email": "walt@breakingbad.com"}, nil); res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a mailer in prod, got %d", res.StatusCode)
+	}
+}
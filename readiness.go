@@ -1,9 +1,86 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
 
-func handleReadiness(w http.ResponseWriter, r *http.Request) {
+// readinessTimeout caps each readiness check, so a hung database makes
+// the probe fail rather than hang.
+const readinessTimeout = 2 * time.Second
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// handle function for /api/livez and /api/healthz. The process is alive if
+// it can answer at all.
+func handleLivez(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+
+// handleReadyz reports whether this instance can serve traffic: the
+// database answers and its schema is the one this binary was built for.
+// Any failing check turns the response into a 503. A store with no
+// database behind it, like the in-memory one, has nothing to check.
+func (cfg *apiConfig) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) error{}
+	if cfg.dbConn != nil {
+		checks["database"] = func(ctx context.Context) error {
+			return cfg.dbConn.PingContext(ctx)
+		}
+		checks["migrations"] = cfg.checkMigrations
+	}
+
+	response := readinessResponse{Status: "ok", Checks: map[string]checkResult{}}
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		start := time.Now()
+		err := check(ctx)
+		cancel()
+
+		result := checkResult{Status: "ok", Duration: time.Since(start).String()}
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			response.Status = "unavailable"
+		}
+		response.Checks[name] = result
+	}
+
+
+	w.Header().Set("Cache-Control", "no-store")
+	if response.Status != "ok" {
+		respondWithJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+
+func (cfg *apiConfig) checkMigrations(ctx context.Context) error {
+	expected, err := latestMigration(cfg.backend)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigration(ctx, cfg.dbConn)
+	if err != nil {
+		return err
+	}
+	if applied != expected {
+		return fmt.Errorf("schema is at version %d, expected %d", applied, expected)
+	}
+	return nil
+}
@@ -2,13 +2,11 @@ package main
 
 import (
 	"net/http"
-	"os"
 )
 
 // handle function for /admin/reset endpoint
 func (cfg *apiConfig) handleReset(w http.ResponseWriter, r *http.Request) {
-	platform := os.Getenv("PLATFORM")
-	if platform != "dev" {
+	if cfg.config.Platform != "dev" {
 		respondWithError(w, http.StatusForbidden, "This operation is not allowed in non-development environment", nil)
 		return
 	}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/chirptext"
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	Body       string    `json:"body"`
+	WrittenAt  time.Time `json:"written_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// handleUpdateChirp lets the author replace a chirp's body. The old body is
+// kept as a revision.
+func (cfg *apiConfig) handleUpdateChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse chirpID", err)
+		return
+	}
+
+
+	userID := requestUserID(r)
+
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+
+	to_update_chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		return
+	}
+
+
+	if to_update_chirp.UserID.UUID != userID {
+		respondWithError(w, http.StatusForbidden, "You are not authorized to edit this chirp", nil)
+		return
+	}
+
+
+	// Editing is a perk of some tiers only
+	policy, err := cfg.tierPolicy(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+	if !policy.CanEdit {
+		respondWithError(w, http.StatusForbidden, "Editing chirps needs Chirpy Red", nil)
+		return
+	}
+
+
+	// Validate chirp length
+	body := chirptext.Normalize(params.Body)
+	if length, ok := validateChirps(body, policy.MaxChirpLength); !ok {
+		respondChirpTooLong(w, length, policy.MaxChirpLength)
+		return
+	}
+
+
+	// Run the body through the moderation filters
+	moderated := cfg.moderator.Moderate(body)
+	if moderated.Rejected {
+		slog.InfoContext(r.Context(), "Rejected chirp edit", slog.String("chirp_id", chirpID.String()), slog.Any("matches", moderated.Matches))
+		respondWithError(w, http.StatusBadRequest, "Chirp violates the content rules", nil)
+		return
+	}
+
+
+	chirp, err := cfg.db.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:   chirpID,
+		Body: moderated.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't update chirp", err)
+		return
+	}
+
+
+	updated_chirp := []Chirp{chirpFromDB(chirp)}
+	if err := cfg.markLikedByMe(r.Context(), updated_chirp, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated_chirp[0])
+}
+
+
+// handleGetChirpRevisions lists the bodies a chirp had before its edits,
+// oldest first.
+func (cfg *apiConfig) handleGetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse chirpID", err)
+		return
+	}
+
+
+	if _, err := cfg.db.GetChirpByID(r.Context(), chirpID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		return
+	}
+
+
+	rows, err := cfg.db.ListChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve revisions", err)
+		return
+	}
+
+	revisions := []ChirpRevision{}
+	for _, row := range rows {
+		revisions = append(revisions, ChirpRevision{
+			Body:       row.Body,
+			WrittenAt:  row.WrittenAt,
+			ReplacedAt: row.ReplacedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, revisions)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateChirp(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+	chirp := postChirp(t, server, walt, "I am the one who knocks")
+	url := server.URL + "/api/chirps/" + chirp.ID.String()
+	edit := func(user testUser, body string, out any) int {
+		return doJSON(t, "PUT", url, user.Token, map[string]string{"body": body}, out).StatusCode
+	}
+
+	if chirp.Edited {
+		t.Error("expected a new chirp not to be marked edited")
+	}
+	if code := edit(walt, "I am the danger", nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 editing without Chirpy Red, got %d", code)
+	}
+
+	upgrade(t, server, walt)
+	upgrade(t, server, jesse)
+
+	if res := doJSON(t, "PUT", url, "", map[string]string{"body": "Say my name"}, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+	if code := edit(jesse, "Yo", nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 editing someone else's chirp, got %d", code)
+	}
+
+	var edited Chirp
+	if code := edit(walt, "I am the danger", &edited); code != http.StatusOK {
+		t.Fatalf("expected 200 editing, got %d", code)
+	}
+	if edited.ID != chirp.ID || edited.Body != "I am the danger" || !edited.Edited {
+		t.Errorf("unexpected edited chirp %+v", edited)
+	}
+	var got Chirp
+	doJSON(t, "GET", url, "", nil, &got)
+	if got.Body != "I am the danger" || !got.Edited {
+		t.Errorf("expected the edit to stick, got %+v", got)
+	}
+
+	if code := edit(walt, strings.Repeat("a", 501), nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a long chirp, got %d", code)
+	}
+	if res := doJSON(t, "PUT", server.URL+"/api/chirps/"+uuid.NewString(), walt.Token, map[string]string{"body": "hi"}, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 editing an unknown chirp, got %d", res.StatusCode)
+	}
+
+	// A tombstone can't be brought back by editing it
+	if res := doJSON(t, "DELETE", url, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 deleting, got %d", res.StatusCode)
+	}
+	if code := edit(walt, "Say my name", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 editing a deleted chirp, got %d", code)
+	}
+}
+
+func TestChirpRevisions(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	upgrade(t, server, walt)
+	chirp := postChirp(t, server, walt, "first")
+	url := server.URL + "/api/chirps/" + chirp.ID.String()
+
+	var revisions []ChirpRevision
+	doJSON(t, "GET", url+"/revisions", "", nil, &revisions)
+	if revisions == nil || len(revisions) != 0 {
+		t.Errorf("expected no revisions before editing, got %+v", revisions)
+	}
+
+	for _, body := range []string{"second", "third"} {
+		if res := doJSON(t, "PUT", url, walt.Token, map[string]string{"body": body}, nil); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 editing, got %d", res.StatusCode)
+		}
+	}
+
+	// Oldest first, each replaced when the next was written
+	revisions = nil
+	if res := doJSON(t, "GET", url+"/revisions", "", nil, &revisions); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if len(revisions) != 2 || revisions[0].Body != "first" || revisions[1].Body != "second" {
+		t.Fatalf("unexpected revisions %+v", revisions)
+	}
+	if !revisions[0].WrittenAt.Equal(chirp.CreatedAt) || revisions[0].ReplacedAt.After(revisions[1].ReplacedAt) {
+		t.Errorf("unexpected revision times %+v", revisions)
+	}
+
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/"+uuid.NewString()+"/revisions", "", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown chirp, got %d", res.StatusCode)
+	}
+
+	// The old bodies go with the chirp
+	if res := doJSON(t, "DELETE", url, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 deleting, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "GET", url+"/revisions", "", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted chirp's revisions, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "net/http"
+
+// routes registers every endpoint and wraps the mux in the middleware
+// stack. The order matters: the access log and metrics must see the request
+// the mux routed, so nothing between them and the mux may replace it.
+func (cfg *apiConfig) routes() http.Handler {
+	mux := http.NewServeMux()
+	fsHandler := cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(cfg.config.FilepathRoot))))
+	mux.Handle("/app/", fsHandler)
+
+	// endpoints
+	mux.HandleFunc("GET /admin/metrics", cfg.handleMetrics)
+	mux.HandleFunc("POST /admin/reset", cfg.handleReset)
+	mux.HandleFunc("GET /admin/moderation/rules", cfg.handleListModerationRules)
+	mux.HandleFunc("POST /admin/moderation/rules", cfg.handleCreateModerationRule)
+	mux.HandleFunc("DELETE /admin/moderation/rules/{ruleID}", cfg.handleDeleteModerationRule)
+	mux.HandleFunc("POST /admin/moderation/reload", cfg.handleReloadModeration)
+
+	mux.HandleFunc("GET /api/healthz", handleLivez)
+	mux.HandleFunc("GET /api/livez", handleLivez)
+	mux.HandleFunc("GET /api/readyz", cfg.handleReadyz)
+	mux.HandleFunc("GET /metrics", cfg.handlePrometheusMetrics)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handleJWKS)
+
+	mux.Handle("POST /api/chirps", requireAuth(cfg.handleCreateChirp))
+	mux.Handle("GET /api/chirps", optionalAuth(cfg.handleGetChirps))
+	mux.Handle("GET /api/chirps/search", optionalAuth(cfg.handleSearchChirps))
+	mux.Handle("GET /api/chirps/{chirpID}", optionalAuth(cfg.handleGetChirpByID))
+	mux.Handle("PUT /api/chirps/{chirpID}", requireAuth(cfg.handleUpdateChirp))
+	mux.Handle("DELETE /api/chirps/{chirpID}", requireAuth(cfg.handleDeleteChirp))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.handleGetChirpRevisions)
+	mux.Handle("GET /api/chirps/{chirpID}/thread", optionalAuth(cfg.handleGetThread))
+	mux.Handle("POST /api/chirps/{chirpID}/likes", requireAuth(cfg.handleLikeChirp))
+	mux.Handle("DELETE /api/chirps/{chirpID}/likes", requireAuth(cfg.handleUnlikeChirp))
+
+	mux.HandleFunc("POST /api/users", cfg.handleCreateUser)
+	mux.Handle("PUT /api/users", requireAuth(cfg.handleUpdateUserInfo))
+	mux.Handle("GET /api/users/me/subscription", requireAuth(cfg.handleGetSubscription))
+	mux.Handle("GET /api/users/me/scheduled_chirps", requireAuth(cfg.handleListScheduledChirps))
+	mux.Handle("DELETE /api/users/me/scheduled_chirps/{scheduledID}", requireAuth(cfg.handleCancelScheduledChirp))
+	mux.HandleFunc("POST /api/login", cfg.handleLogin)
+	mux.HandleFunc("POST /api/refresh", cfg.handleRefreshToken)
+	mux.HandleFunc("POST /api/revoke", cfg.handleRevokeToken)
+	mux.HandleFunc("POST /api/password-reset/request", cfg.handleRequestPasswordReset)
+	mux.HandleFunc("POST /api/password-reset/confirm", cfg.handleConfirmPasswordReset)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlePolkaWebhook)
+
+	mux.Handle("POST /api/users/{userID}/follow", requireAuth(cfg.handleFollowUser))
+	mux.Handle("DELETE /api/users/{userID}/follow", requireAuth(cfg.handleUnfollowUser))
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.handleGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.handleGetFollowing)
+	mux.Handle("GET /api/timeline", requireAuth(cfg.handleGetTimeline))
+
+
+	return middlewareRequestID(cfg.middlewareAuthenticate(middlewareAccessLog(cfg.middlewareInstrument(mux))))
+}
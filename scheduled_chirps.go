@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ScheduledChirp is a chirp waiting to be published. It keeps its id once
+// it is.
+type ScheduledChirp struct {
+	ID			uuid.UUID	`json:"id"`
+	CreatedAt	time.Time	`json:"created_at"`
+	Body		string		`json:"body"`
+	PublishAt	time.Time	`json:"publish_at"`
+}
+
+
+func scheduledChirpFromDB(chirp database.ScheduledChirp) ScheduledChirp {
+	return ScheduledChirp{
+		ID:			chirp.ID,
+		CreatedAt:	chirp.CreatedAt,
+		Body:		chirp.Body,
+		PublishAt:	chirp.PublishAt,
+	}
+}
+
+
+// scheduleChirp queues an already validated and moderated body to be
+// published at publishAt, if the user's tier lets them.
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, r *http.Request, policy config.TierPolicy, body string, publishAt time.Time) {
+	userID := requestUserID(r)
+
+	if policy.MaxScheduled == 0 {
+		respondWithError(w, http.StatusForbidden, "Scheduling chirps needs Chirpy Red", nil)
+		return
+	}
+	pending, err := cfg.db.CountScheduledChirps(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count scheduled chirps", err)
+		return
+	}
+	if pending >= int64(policy.MaxScheduled) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many scheduled chirps", nil)
+		return
+	}
+
+
+	scheduled, err := cfg.db.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+		UserID:		userID,
+		Body:		body,
+		PublishAt:	publishAt.UTC(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't schedule chirp", err)
+		return
+	}
+
+
+	respondWithJSON(w, http.StatusAccepted, scheduledChirpFromDB(scheduled))
+}
+
+
+func (cfg *apiConfig) handleListScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	scheduled, err := cfg.db.ListScheduledChirps(r.Context(), requestUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list scheduled chirps", err)
+		return
+	}
+
+
+	converted := []ScheduledChirp{}
+	for _, chirp := range scheduled {
+		converted = append(converted, scheduledChirpFromDB(chirp))
+	}
+	respondWithJSON(w, http.StatusOK, converted)
+}
+
+
+func (cfg *apiConfig) handleCancelScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	scheduledID, err := uuid.Parse(r.PathValue("scheduledID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse scheduledID", err)
+		return
+	}
+
+
+	// Someone else's scheduled chirp is as invisible as a missing one
+	n, err := cfg.db.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:		scheduledID,
+		UserID:	requestUserID(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't cancel scheduled chirp", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Scheduled chirp not found", nil)
+		return
+	}
+
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+// publishScheduledChirps publishes the scheduled chirps that are due every
+// interval until ctx is done.
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := cfg.db.PublishDueChirps(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Couldn't publish scheduled chirps", slog.Any("error", err))
+		}
+		cfg.metrics.ChirpsCreated.Add(float64(len(published)))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/google/uuid"
+)
+
+func TestScheduledChirps(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Tiers.Red.MaxScheduled = 2
+		cfg.PublishInterval = 10 * time.Millisecond
+	})
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+	url := server.URL + "/api/chirps"
+	schedule := func(user testUser, body string, publishAt time.Time, out any) int {
+		return doJSON(t, "POST", url, user.Token, map[string]any{"body": body, "publish_at": publishAt}, out).StatusCode
+	}
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	if code := schedule(walt, "Tread lightly", tomorrow, nil); code != http.StatusForbidden {
+		t.Errorf("expected 403 scheduling on the free tier, got %d", code)
+	}
+	// A time that has passed publishes straight away
+	if code := schedule(walt, "Tread lightly", time.Now().Add(-time.Hour), nil); code != http.StatusCreated {
+		t.Errorf("expected 201 for a past publish time, got %d", code)
+	}
+
+	upgrade(t, server, walt)
+	var later ScheduledChirp
+	if code := schedule(walt, "Tread lightly", tomorrow, &later); code != http.StatusAccepted {
+		t.Fatalf("expected 202 scheduling, got %d", code)
+	}
+	if later.Body != "Tread lightly" || !later.PublishAt.Equal(tomorrow) {
+		t.Errorf("unexpected scheduled chirp %+v", later)
+	}
+	reply := map[string]any{"body": "Yo", "publish_at": tomorrow, "in_reply_to_id": later.ID}
+	if res := doJSON(t, "POST", url, walt.Token, reply, nil); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 scheduling a reply, got %d", res.StatusCode)
+	}
+
+	var soon ScheduledChirp
+	if code := schedule(walt, "Say my name", time.Now().Add(200*time.Millisecond), &soon); code != http.StatusAccepted {
+		t.Fatalf("expected 202 scheduling, got %d", code)
+	}
+	if code := schedule(walt, "One too many", tomorrow, nil); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 past the scheduling limit, got %d", code)
+	}
+
+	var published Chirp
+	deadline := time.Now().Add(5 * time.Second)
+	for doJSON(t, "GET", url+"/"+soon.ID.String(), "", nil, nil).StatusCode != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the scheduled chirp to be published")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	doJSON(t, "GET", url+"/"+soon.ID.String(), "", nil, &published)
+	if published.Body != "Say my name" || published.UserID != walt.ID {
+		t.Errorf("unexpected published chirp %+v", published)
+	}
+
+	var pending []ScheduledChirp
+	doJSON(t, "GET", server.URL+"/api/users/me/scheduled_chirps", walt.Token, nil, &pending)
+	if len(pending) != 1 || pending[0].ID != later.ID {
+		t.Errorf("expected only the later chirp to be waiting, got %+v", pending)
+	}
+
+	cancel := server.URL + "/api/users/me/scheduled_chirps/" + later.ID.String()
+	if res := doJSON(t, "DELETE", cancel, jesse.Token, nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 cancelling someone else's chirp, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "DELETE", cancel, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 cancelling, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "DELETE", server.URL+"/api/users/me/scheduled_chirps/"+uuid.NewString(), walt.Token, nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown scheduled chirp, got %d", res.StatusCode)
+	}
+}
+
+func TestScheduledChirpsCountTowardsQuota(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Tiers.Red.DailyChirps = 2
+		cfg.Tiers.Red.MaxScheduled = 5
+	})
+	walt := signUp(t, server, "walt@breakingbad.com")
+	upgrade(t, server, walt)
+	url := server.URL + "/api/chirps"
+	soon := time.Now().Add(time.Hour)
+	schedule := func(body string, out any) int {
+		return doJSON(t, "POST", url, walt.Token, map[string]any{"body": body, "publish_at": soon}, out).StatusCode
+	}
+
+	var first ScheduledChirp
+	if code := schedule("Say my name", &first); code != http.StatusAccepted {
+		t.Fatalf("expected 202 scheduling, got %d", code)
+	}
+	if code := schedule("Heisenberg", nil); code != http.StatusAccepted {
+		t.Fatalf("expected 202 scheduling, got %d", code)
+	}
+	if code := schedule("You're goddamn right", nil); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 scheduling past the daily quota, got %d", code)
+	}
+	if res := doJSON(t, "POST", url, walt.Token, map[string]string{"body": "Tread lightly"}, nil); res.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected pending chirps to use up the daily quota, got %d", res.StatusCode)
+	}
+
+	if res := doJSON(t, "DELETE", server.URL+"/api/users/me/scheduled_chirps/"+first.ID.String(), walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 cancelling, got %d", res.StatusCode)
+	}
+	postChirp(t, server, walt, "Tread lightly")
+
+	// A chirp going out next week draws on that day's quota, not today's
+	nextWeek := map[string]any{"body": "Better call Saul", "publish_at": time.Now().Add(7 * 24 * time.Hour)}
+	if res := doJSON(t, "POST", url, walt.Token, nextWeek, nil); res.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202 scheduling next week with today's quota spent, got %d", res.StatusCode)
+	}
+}
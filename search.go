@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/NachoGz/chirpy/internal/chirptext"
+	"github.com/NachoGz/chirpy/internal/database"
+)
+
+// SearchResult is a chirp matching a search. Highlight is the body as
+// escaped HTML with the matching words wrapped in <mark> tags.
+type SearchResult struct {
+	Chirp
+	Rank      float32 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// SearchPage is one page of search results, with cursors like ChirpPage.
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor *string        `json:"next_cursor"`
+	PrevCursor *string        `json:"prev_cursor"`
+}
+
+func (cfg *apiConfig) handleSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	viewer := viewerID(r)
+
+	// Chirps are stored in NFC, so the query has to be too
+	tsQuery, err := buildTSQuery(chirptext.Normalize(query.Get("q")))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	authorID, err := parseAuthorID(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid author_id format", err)
+		return
+	}
+
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp", err)
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "until must be an RFC 3339 timestamp", err)
+		return
+	}
+
+	// Ranked results have no stable (created_at, id) order to hang a
+	// cursor on, so search cursors hold an offset instead. The bare offset
+	// parameter is still accepted for older clients.
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	offset := 0
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := decodeOffsetCursor(cursorStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		offset = cursor.Offset
+	} else if offsetStr := query.Get("offset"); offsetStr != "" {
+		parsed, err := strconv.ParseInt(offsetStr, 10, 32)
+		if err != nil || parsed < 0 {
+			respondWithError(w, http.StatusBadRequest, "offset must be a non-negative 32-bit integer", err)
+			return
+		}
+		offset = int(parsed)
+	}
+
+	rows, err := cfg.db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:      tsQuery,
+		AuthorID:   authorID,
+		Since:      since,
+		Until:      until,
+		PageOffset: int32(offset),
+		PageSize:   limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't search chirps", err)
+		return
+	}
+
+	response := SearchPage{Results: []SearchResult{}}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		next := offsetCursor{Offset: offset + int(limit)}.encode()
+		response.NextCursor = &next
+	}
+	if offset > 0 {
+		prev := offsetCursor{Offset: max(offset-int(limit), 0)}.encode()
+		response.PrevCursor = &prev
+	}
+
+	chirps := []Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, chirpFromDB(database.Chirp{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Body:        row.Body,
+			UserID:      row.UserID,
+			LikeCount:   row.LikeCount,
+			InReplyToID: row.InReplyToID,
+			ThreadID:    row.ThreadID,
+			DeletedAt:   row.DeletedAt,
+			EditedAt:    row.EditedAt,
+		}))
+	}
+	if err := cfg.markLikedByMe(r.Context(), chirps, viewer); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
+		return
+	}
+
+	for i, row := range rows {
+		response.Results = append(response.Results, SearchResult{
+			Chirp:     chirps[i],
+			Rank:      row.Rank,
+			Highlight: row.Highlight,
+		})
+	}
+
+	setPageLinks(w, r, response.NextCursor, response.PrevCursor)
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func parseTimeParam(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
+
+// buildTSQuery turns a user search string into to_tsquery syntax. Terms are
+// ANDed together, "quoted phrases" must appear in order, a trailing * makes
+// a prefix match and a leading - excludes a term. Anything that isn't a
+// letter or digit is dropped so users can't inject tsquery operators.
+func buildTSQuery(q string) (string, error) {
+	var parts []string
+
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			phrase := q[1:]
+			q = ""
+			if end >= 0 {
+				phrase, q = phrase[:end], phrase[end+1:]
+			}
+
+			var words []string
+			for _, word := range strings.Fields(phrase) {
+				if lexeme := sanitizeLexeme(word); lexeme != "" {
+					words = append(words, lexeme)
+				}
+			}
+			if len(words) > 0 {
+				parts = append(parts, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(q, unicode.IsSpace)
+		if end < 0 {
+			end = len(q)
+		}
+		term := q[:end]
+		q = q[end:]
+
+		negate := strings.HasPrefix(term, "-")
+		prefix := strings.HasSuffix(term, "*")
+		lexeme := sanitizeLexeme(term)
+		if lexeme == "" {
+			continue
+		}
+		if prefix {
+			lexeme += ":*"
+		}
+		if negate {
+			lexeme = "!" + lexeme
+		}
+		parts = append(parts, lexeme)
+	}
+
+	if len(parts) == 0 {
+		return "", errors.New("q must contain at least one search term")
+	}
+	return strings.Join(parts, " & "), nil
+}
+
+func sanitizeLexeme(word string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, word)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSearchChirps(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+
+	knocks := postChirp(t, server, walt, "I am the one who knocks")
+	postChirp(t, server, jesse, "Yo, who is it")
+	postChirp(t, server, walt, "Say my name")
+
+	search := server.URL + "/api/chirps/search?q="
+
+	var page SearchPage
+	if res := doJSON(t, "GET", search+"knocks", "", nil, &page); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if len(page.Results) != 1 || page.Results[0].ID != knocks.ID {
+		t.Fatalf("expected only %s, got %+v", knocks.ID, page.Results)
+	}
+	if !strings.Contains(page.Results[0].Highlight, "<mark>knocks</mark>") {
+		t.Errorf("expected the match to be marked, got %q", page.Results[0].Highlight)
+	}
+	if page.NextCursor != nil || page.PrevCursor != nil {
+		t.Errorf("expected a single page, got %+v", page)
+	}
+
+	page = SearchPage{}
+	doJSON(t, "GET", search+"who&author_id="+jesse.ID.String(), "", nil, &page)
+	if len(page.Results) != 1 || page.Results[0].UserID != jesse.ID {
+		t.Errorf("expected only jesse's chirp, got %+v", page.Results)
+	}
+
+	page = SearchPage{}
+	doJSON(t, "GET", search+"nobody", "", nil, &page)
+	if page.Results == nil || len(page.Results) != 0 {
+		t.Errorf("expected an empty list, got %+v", page.Results)
+	}
+}
+
+func TestSearchChirpsPages(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	for _, body := range []string{"blue sky", "blue crystal", "blue magic"} {
+		postChirp(t, server, user, body)
+	}
+
+	search := server.URL + "/api/chirps/search?q=blue&limit=2"
+	seen := map[string]bool{}
+
+	var page SearchPage
+	res := doJSON(t, "GET", search, "", nil, &page)
+	if len(page.Results) != 2 {
+		t.Fatalf("expected 2 results on the first page, got %d", len(page.Results))
+	}
+	if page.NextCursor == nil || *page.NextCursor != res.Header.Get("X-Next-Cursor") || page.PrevCursor != nil {
+		t.Fatalf("expected only a next cursor, matching the header, got %+v", page)
+	}
+	for _, result := range page.Results {
+		seen[result.Body] = true
+	}
+
+	next := *page.NextCursor
+	page = SearchPage{}
+	res = doJSON(t, "GET", search+"&cursor="+url.QueryEscape(next), "", nil, &page)
+	if len(page.Results) != 1 || seen[page.Results[0].Body] {
+		t.Fatalf("unexpected second page %+v", page.Results)
+	}
+	if page.NextCursor != nil || res.Header.Get("X-Next-Cursor") != "" {
+		t.Error("expected no cursor after the last page")
+	}
+	if page.PrevCursor == nil {
+		t.Error("expected a cursor back to the first page")
+	}
+
+	page = SearchPage{}
+	doJSON(t, "GET", search+"&offset=2", "", nil, &page)
+	if len(page.Results) != 1 {
+		t.Errorf("expected the legacy offset to skip 2 results, got %d", len(page.Results))
+	}
+}
+
+func TestSearchChirpsBadParams(t *testing.T) {
+	server := newTestServer(t)
+	search := server.URL + "/api/chirps/search"
+
+	for _, query := range []string{
+		"",
+		"?q=-",
+		"?q=blue&author_id=nope",
+		"?q=blue&since=yesterday",
+		"?q=blue&until=tomorrow",
+		"?q=blue&limit=0",
+		"?q=blue&offset=-1",
+		"?q=blue&offset=2147483648",
+		"?q=blue&cursor=nope",
+	} {
+		if res := doJSON(t, "GET", search+query, "", nil, nil); res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// freeAddr finds a local address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// slowServer starts serve with a handler that blocks until release is
+// closed. The returned channels report when a request has started and
+// what serve returned.
+func slowServer(t *testing.T, ctx context.Context, drain time.Duration) (addr string, started <-chan struct{}, release chan struct{}, served <-chan error) {
+	t.Helper()
+
+	startedCh := make(chan struct{}, 1)
+	release = make(chan struct{})
+	server := &http.Server{
+		Addr: freeAddr(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startedCh <- struct{}{}
+			<-release
+			io.WriteString(w, "done")
+		}),
+	}
+	servedCh := make(chan error, 1)
+	go func() { servedCh <- serve(ctx, server, drain) }()
+
+	// Wait for the listener
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", server.Addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("server never started: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return server.Addr, startedCh, release, servedCh
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, started, release, served := slowServer(t, ctx, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + addr)
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		responses <- result{string(body), err}
+	}()
+	<-started
+
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("expected serve to wait for the request, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if res := <-responses; res.err != nil || res.body != "done" {
+		t.Errorf("expected the in-flight request to finish, got %q, %v", res.body, res.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestServeGivesUpAfterDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, started, release, served := slowServer(t, ctx, 50*time.Millisecond)
+	defer close(release)
+
+	go func() {
+		if res, err := http.Get("http://" + addr); err == nil {
+			res.Body.Close()
+		}
+	}()
+	<-started
+
+	cancel()
+	select {
+	case err := <-served:
+		if err == nil {
+			t.Error("expected an error when requests outlast the drain")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected serve to give up after the drain deadline")
+	}
+}
@@ -0,0 +1,26 @@
+-- name: UpdateChirpBody :one
+-- The current body is locked and copied into chirp_revisions in the same
+-- statement, so concurrent edits can't lose a revision.
+WITH previous AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, written_at, replaced_at)
+    SELECT
+        gen_random_uuid(),
+        current.id,
+        current.body,
+        COALESCE(current.edited_at, current.created_at),
+        NOW()
+    FROM (
+        SELECT * FROM chirps
+        WHERE chirps.id = sqlc.arg('id') AND chirps.deleted_at IS NULL
+        FOR UPDATE
+    ) AS current
+)
+UPDATE chirps
+SET body = sqlc.arg('body'), updated_at = NOW(), edited_at = NOW()
+WHERE chirps.id = sqlc.arg('id') AND chirps.deleted_at IS NULL
+RETURNING *;
+
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC;
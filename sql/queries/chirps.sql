@@ -1,29 +1,98 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
-    gen_random_uuid(),
+-- Root chirps start their own thread, so the new id is generated up front
+-- to be usable as thread_id too.
+WITH new_chirp AS (
+    SELECT gen_random_uuid() AS id
+)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, thread_id)
+SELECT
+    new_chirp.id,
     NOW(),
     NOW(),
-    $1,
-    $2
-)
+    sqlc.arg('body'),
+    sqlc.arg('user_id'),
+    sqlc.narg('in_reply_to_id'),
+    COALESCE(sqlc.narg('thread_id')::uuid, new_chirp.id)
+FROM new_chirp
 RETURNING *;
 
--- name: GetAllChirps :many
-SELECT * FROM chirps 
-ORDER BY created_at ASC;
+-- name: CountChirpsSince :one
+-- Deleted chirps still count, so deleting doesn't make room in a quota.
+SELECT COUNT(*) FROM chirps
+WHERE user_id = sqlc.arg('user_id') AND created_at > sqlc.arg('since')::timestamp;
+
+-- name: ListChirpsAfter :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_size');
 
--- name: GetAllChirpsByID :many
-SELECT * FROM chirps 
-WHERE user_id = $1
-ORDER BY created_at ASC;
+-- name: ListChirpsBefore :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
 
 
 -- name: GetChirpByID :one
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: DeleteChirp :one
-DELETE from chirps
-where id = $1
-RETURNING *;
\ No newline at end of file
+-- Old bodies and likes go with the chirp; the tombstone keeps nothing the
+-- author wrote and no one's reaction to it. The chirp_likes_count trigger
+-- takes like_count down to zero once the statement has run, so the
+-- returned row still has the old count.
+WITH revisions AS (
+    DELETE FROM chirp_revisions
+    WHERE chirp_revisions.chirp_id = $1
+), likes AS (
+    DELETE FROM chirp_likes
+    WHERE chirp_likes.chirp_id = $1
+)
+UPDATE chirps
+SET deleted_at = NOW(), updated_at = NOW(), body = ''
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+RETURNING *;
+
+-- name: GetChirpThreadID :one
+SELECT thread_id FROM chirps
+WHERE id = $1;
+
+-- name: GetThread :many
+SELECT * FROM chirps
+WHERE thread_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: SearchChirps :many
+-- highlight is the body HTML-escaped, with the matches wrapped in <mark>
+-- tags, so clients can render it as it is. The match has to use the same
+-- to_tsvector('english', body) expression as the index from 006, or the
+-- planner won't use it.
+SELECT
+    chirps.*,
+    ts_rank(to_tsvector('english', chirps.body), query)::real AS rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    )::text AS highlight
+FROM chirps, to_tsquery('english', sqlc.arg('query')) AS query
+WHERE to_tsvector('english', chirps.body) @@ query
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size') OFFSET sqlc.arg('page_offset');
@@ -0,0 +1,20 @@
+-- name: ListModerationRules :many
+SELECT * FROM moderation_rules
+ORDER BY created_at ASC, id ASC;
+
+-- name: CreateModerationRule :one
+-- A rule that already exists returns no row.
+INSERT INTO moderation_rules (id, created_at, kind, pattern, action)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (kind, pattern) DO NOTHING
+RETURNING *;
+
+-- name: DeleteModerationRule :execrows
+DELETE FROM moderation_rules
+WHERE id = $1;
@@ -0,0 +1,38 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    $3
+);
+
+-- name: ResetPassword :one
+-- Uses up the token, along with every other unused token of its user, sets
+-- the new password and revokes the user's refresh tokens so sessions
+-- started with the old password end. Returns no rows when the token is
+-- unknown, used or expired.
+WITH claimed AS (
+    UPDATE password_reset_tokens
+    SET used_at = NOW()
+    WHERE password_reset_tokens.token_hash = sqlc.arg('token_hash')
+    AND password_reset_tokens.used_at IS NULL
+    AND password_reset_tokens.expires_at > NOW()
+    RETURNING password_reset_tokens.user_id
+), others AS (
+    UPDATE password_reset_tokens
+    SET used_at = NOW()
+    WHERE password_reset_tokens.user_id IN (SELECT claimed.user_id FROM claimed)
+    AND password_reset_tokens.token_hash <> sqlc.arg('token_hash')
+    AND password_reset_tokens.used_at IS NULL
+), updated AS (
+    UPDATE users
+    SET hashed_password = sqlc.arg('hashed_password'), updated_at = NOW()
+    WHERE users.id IN (SELECT claimed.user_id FROM claimed)
+), revoked AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(), updated_at = NOW()
+    WHERE refresh_tokens.user_id IN (SELECT claimed.user_id FROM claimed)
+    AND refresh_tokens.revoked_at IS NULL
+)
+SELECT claimed.user_id FROM claimed;
@@ -0,0 +1,44 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, user_id, body, publish_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: ListScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = $1
+ORDER BY publish_at ASC, id ASC;
+
+-- name: CountScheduledChirps :one
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = $1;
+
+-- name: CountScheduledChirpsBetween :one
+-- Counts the user's scheduled chirps due strictly between after and before.
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = sqlc.arg('user_id')
+AND publish_at > sqlc.arg('after')::timestamp
+AND publish_at < sqlc.arg('before')::timestamp;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2;
+
+-- name: PublishDueChirps :many
+-- Moves every scheduled chirp whose time has come into chirps, each
+-- starting its own thread. Deleting and inserting in one statement means
+-- a chirp is published once even with several publishers running.
+WITH due AS (
+    DELETE FROM scheduled_chirps
+    WHERE publish_at <= NOW()
+    RETURNING *
+)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, thread_id)
+SELECT due.id, NOW(), NOW(), due.body, due.user_id, due.id
+FROM due
+RETURNING *;
@@ -0,0 +1,78 @@
+-- name: RenewSubscription :one
+-- Applies a Polka upgrade or renewal: records the event and then pushes
+-- back the expiry of the user's open subscription, or opens a new one, and
+-- makes the user Chirpy Red. The event is only recorded together with the
+-- change, so a delivery is either applied and remembered or neither. An
+-- expiry earlier than the current one is ignored, so a late redelivery
+-- can't shorten a subscription. Returns no rows when the event was applied
+-- before or the user doesn't exist.
+WITH claimed AS (
+    INSERT INTO polka_events (id, event, received_at)
+    SELECT sqlc.arg('event_id')::text, sqlc.arg('event')::text, NOW()
+    FROM users
+    WHERE users.id = sqlc.arg('user_id')
+    ON CONFLICT (id) DO NOTHING
+    RETURNING polka_events.id
+), renewed AS (
+    UPDATE subscriptions
+    SET expires_at = GREATEST(subscriptions.expires_at, sqlc.arg('expires_at')::timestamp), updated_at = NOW()
+    WHERE subscriptions.user_id = sqlc.arg('user_id') AND subscriptions.ended_at IS NULL
+        AND EXISTS (SELECT 1 FROM claimed)
+    RETURNING subscriptions.*
+), opened AS (
+    INSERT INTO subscriptions (id, user_id, started_at, updated_at, expires_at)
+    SELECT gen_random_uuid(), users.id, NOW(), NOW(), sqlc.arg('expires_at')::timestamp
+    FROM users
+    WHERE users.id = sqlc.arg('user_id')
+        AND EXISTS (SELECT 1 FROM claimed) AND NOT EXISTS (SELECT 1 FROM renewed)
+    RETURNING *
+), upgraded AS (
+    UPDATE users
+    SET is_chirpy_red = TRUE, updated_at = NOW()
+    WHERE users.id = sqlc.arg('user_id') AND EXISTS (SELECT 1 FROM claimed)
+)
+SELECT * FROM renewed
+UNION ALL
+SELECT * FROM opened;
+
+-- name: CancelSubscription :execrows
+-- Applies a Polka downgrade: records the event, ends the user's open
+-- subscription and takes away Chirpy Red, all or nothing. Affects no rows
+-- when the event was applied before or the user doesn't exist.
+WITH claimed AS (
+    INSERT INTO polka_events (id, event, received_at)
+    SELECT sqlc.arg('event_id')::text, sqlc.arg('event')::text, NOW()
+    FROM users
+    WHERE users.id = sqlc.arg('user_id')
+    ON CONFLICT (id) DO NOTHING
+    RETURNING polka_events.id
+), ended AS (
+    UPDATE subscriptions
+    SET ended_at = NOW(), end_reason = 'downgraded', updated_at = NOW()
+    WHERE subscriptions.user_id = sqlc.arg('user_id') AND subscriptions.ended_at IS NULL
+        AND EXISTS (SELECT 1 FROM claimed)
+)
+UPDATE users
+SET is_chirpy_red = FALSE, updated_at = NOW()
+WHERE users.id = sqlc.arg('user_id') AND EXISTS (SELECT 1 FROM claimed);
+
+-- name: ExpireSubscriptions :many
+-- Ends every open subscription that expired before the cutoff and takes
+-- Chirpy Red away from its user.
+WITH expired AS (
+    UPDATE subscriptions
+    SET ended_at = NOW(), end_reason = 'expired', updated_at = NOW()
+    WHERE subscriptions.ended_at IS NULL AND subscriptions.expires_at < sqlc.arg('cutoff')::timestamp
+    RETURNING subscriptions.*
+), downgraded AS (
+    UPDATE users
+    SET is_chirpy_red = FALSE, updated_at = NOW()
+    WHERE users.id IN (SELECT expired.user_id FROM expired)
+)
+SELECT * FROM expired;
+
+-- name: GetSubscriptionsByUser :many
+-- The user's subscription history, newest first.
+SELECT * FROM subscriptions
+WHERE user_id = $1
+ORDER BY started_at DESC, id DESC;
@@ -26,8 +26,3 @@ UPDATE users
 SET email = $2, hashed_password = $3, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
-
--- name: UpgradeToChirpyRed :exec
-UPDATE users
-SET is_chirpy_red = TRUE, updated_at = NOW()
-WHERE id = $1;
\ No newline at end of file
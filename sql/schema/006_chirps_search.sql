@@ -0,0 +1,8 @@
+-- +goose Up
+-- Expression index rather than a stored column so that the tsvector isn't
+-- carried along by every SELECT * on chirps. Queries must use the exact same
+-- to_tsvector('english', body) expression for the planner to pick it up.
+CREATE INDEX chirps_body_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_body_search_idx;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Only SHA-256 digests of refresh tokens are kept, so a copy of the table
+-- can't be used to mint sessions. Existing tokens stay valid because clients
+-- still hold the raw value and the server hashes it on every lookup.
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+ALTER TABLE refresh_tokens RENAME COLUMN replaced_by TO replaced_by_hash;
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex'),
+    replaced_by_hash = encode(sha256(convert_to(replaced_by_hash, 'UTF8')), 'hex');
+
+-- +goose Down
+-- Digests can't be turned back into tokens, so everyone has to log in again.
+DELETE FROM refresh_tokens;
+ALTER TABLE refresh_tokens RENAME COLUMN replaced_by_hash TO replaced_by;
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
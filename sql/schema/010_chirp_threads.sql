@@ -0,0 +1,19 @@
+-- +goose Up
+-- thread_id is the id of the root chirp of the conversation (a root's
+-- thread_id is its own id), so a whole thread is one indexed lookup.
+-- Deleting a chirp now leaves a tombstone (deleted_at set, body cleared) so
+-- replies to it keep their place in the tree.
+ALTER TABLE chirps ADD COLUMN in_reply_to_id UUID REFERENCES chirps (id) ON DELETE SET NULL;
+ALTER TABLE chirps ADD COLUMN thread_id UUID;
+UPDATE chirps SET thread_id = id;
+ALTER TABLE chirps ALTER COLUMN thread_id SET NOT NULL;
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX chirps_thread_id_idx ON chirps (thread_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_thread_id_idx;
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+ALTER TABLE chirps DROP COLUMN deleted_at;
+ALTER TABLE chirps DROP COLUMN thread_id;
+ALTER TABLE chirps DROP COLUMN in_reply_to_id;
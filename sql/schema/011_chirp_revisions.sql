@@ -0,0 +1,18 @@
+-- +goose Up
+-- Every edit keeps the body it replaced. written_at is when that body was
+-- first posted or last edited, replaced_at when the edit superseded it.
+CREATE TABLE chirp_revisions(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps (id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    written_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_revisions_chirp_id_idx ON chirp_revisions (chirp_id, replaced_at);
+
+ALTER TABLE chirps ADD COLUMN edited_at TIMESTAMP DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN edited_at;
+DROP TABLE IF EXISTS chirp_revisions;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE moderation_rules(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('word', 'regex')),
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'reject')),
+    UNIQUE (kind, pattern)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS moderation_rules;
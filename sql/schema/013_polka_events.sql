@@ -0,0 +1,12 @@
+-- +goose Up
+-- Polka redelivers events it doesn't see acknowledged. The id of every
+-- event handled is kept so a repeat is acknowledged without being applied
+-- twice.
+CREATE TABLE polka_events(
+    id TEXT PRIMARY KEY,
+    event TEXT NOT NULL,
+    received_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS polka_events;
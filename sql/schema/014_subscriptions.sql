@@ -0,0 +1,28 @@
+-- +goose Up
+-- One row per uninterrupted run of Chirpy Red. A renewal pushes back
+-- expires_at; a downgrade or lapse sets ended_at, and the next upgrade
+-- opens a new row, so the table is the user's subscription history.
+-- users.is_chirpy_red stays in step with whether a row is open.
+CREATE TABLE subscriptions(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    end_reason TEXT CHECK (end_reason IN ('downgraded', 'expired')),
+    CHECK ((ended_at IS NULL) = (end_reason IS NULL))
+);
+
+CREATE UNIQUE INDEX subscriptions_open_idx ON subscriptions (user_id) WHERE ended_at IS NULL;
+CREATE INDEX subscriptions_user_id_idx ON subscriptions (user_id, started_at);
+
+-- Red users from before subscriptions were tracked get a month to be
+-- renewed by Polka
+INSERT INTO subscriptions (id, user_id, started_at, updated_at, expires_at)
+SELECT gen_random_uuid(), id, updated_at, NOW(), NOW() + INTERVAL '1 month'
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE IF EXISTS subscriptions;
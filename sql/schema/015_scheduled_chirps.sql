@@ -0,0 +1,16 @@
+-- +goose Up
+-- Chirps written to be published later. Once publish_at has passed the
+-- publisher moves each one into chirps, keeping its id.
+CREATE TABLE scheduled_chirps(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    publish_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX scheduled_chirps_publish_at_idx ON scheduled_chirps (publish_at);
+CREATE INDEX scheduled_chirps_user_id_publish_at_idx ON scheduled_chirps (user_id, publish_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS scheduled_chirps;
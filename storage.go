@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/NachoGz/chirpy/internal/sqlitestore"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// backend is one of the databases chirpy can keep its data in.
+type backend struct {
+	name       string
+	dialect    goose.Dialect
+	migrations fs.FS
+	// newLocker returns a lock that keeps replicas from migrating at the
+	// same time. It is nil where only one process can use the database.
+	newLocker func() (lock.SessionLocker, error)
+	queries   func(db *sql.DB) database.Querier
+}
+
+var postgresBackend = backend{
+	name:       "postgres",
+	dialect:    goose.DialectPostgres,
+	migrations: mustSub(migrationFS, "sql/schema"),
+	newLocker: func() (lock.SessionLocker, error) {
+		return lock.NewPostgresSessionLocker()
+	},
+	queries: func(db *sql.DB) database.Querier {
+		return database.New(db)
+	},
+}
+
+var sqliteBackend = backend{
+	name:       "sqlite",
+	dialect:    goose.DialectSQLite3,
+	migrations: sqlitestore.Migrations,
+	queries: func(db *sql.DB) database.Querier {
+		return sqlitestore.New(db)
+	},
+}
+
+// openDatabase connects to the database DB_URL names. postgres:// and
+// postgresql:// URLs, as well as key=value connection strings, go to
+// PostgreSQL; sqlite:path/to/chirpy.db or sqlite:///abs/path/chirpy.db
+// open a SQLite file, which is created if it doesn't exist.
+func openDatabase(dbURL string) (*sql.DB, backend, error) {
+	scheme, rest, hasScheme := strings.Cut(dbURL, ":")
+	switch {
+	case hasScheme && scheme == "sqlite":
+		path := strings.TrimPrefix(rest, "//")
+		if path == "" {
+			return nil, backend{}, fmt.Errorf("DB_URL %q names no SQLite file", dbURL)
+		}
+		db, err := sqlitestore.Open(path)
+		return db, sqliteBackend, err
+	case hasScheme && (scheme == "postgres" || scheme == "postgresql"),
+		!strings.Contains(dbURL, "://"):
+		db, err := sql.Open("postgres", dbURL)
+		return db, postgresBackend, err
+	default:
+		return nil, backend{}, fmt.Errorf("unsupported DB_URL scheme %q; use postgres:// or sqlite:", scheme)
+	}
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Subscription struct {
+	ID			uuid.UUID	`json:"id"`
+	StartedAt	time.Time	`json:"started_at"`
+	ExpiresAt	time.Time	`json:"expires_at"`
+	EndedAt		*time.Time	`json:"ended_at"`
+	EndReason	string		`json:"end_reason,omitempty"`
+}
+
+// SubscriptionStatus is a user's standing with Chirpy Red. Status is
+// active until the open subscription expires, then grace_period until the
+// expiry job ends it, and inactive with no open subscription.
+type SubscriptionStatus struct {
+	IsChirpyRed			bool			`json:"is_chirpy_red"`
+	Status				string			`json:"status"`
+	ExpiresAt			*time.Time		`json:"expires_at"`
+	GracePeriodEndsAt	*time.Time		`json:"grace_period_ends_at"`
+	History				[]Subscription	`json:"history"`
+}
+
+
+// maxWebhookBody bounds how much of a Polka delivery is read before its
+// signature is checked.
+const maxWebhookBody = 1 << 16
+
+
+// handlePolkaWebhook applies subscription events from Polka. user.upgraded
+// and user.renewed extend the user's subscription to data.expires_at, or
+// by a month when Polka doesn't send one; user.downgraded ends it.
+func (cfg *apiConfig) handlePolkaWebhook(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ID string `json:"id"`
+		Event string `json:"event"`
+		Data struct {
+			UserID uuid.UUID `json:"user_id"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		} `json:"data"`
+	}
+
+	if len(cfg.config.Polka.Secrets) == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Webhooks are not configured", nil)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Couldn't read body", err)
+		return
+	}
+	err = auth.VerifyWebhook(r.Header, body, cfg.config.Polka.Secrets, cfg.config.Polka.Tolerance, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid webhook signature", err)
+		return
+	}
+
+
+	params := parameters{}
+	err = json.Unmarshal(body, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.ID == "" {
+		respondWithError(w, http.StatusBadRequest, "Event id is required", nil)
+		return
+	}
+
+	switch params.Event {
+	case "user.upgraded", "user.renewed", "user.downgraded":
+	default:
+		// Polka can send any event name, so unknown ones share a label
+		cfg.metrics.WebhookEvents.WithLabelValues("other").Inc()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+
+	// Polka retries until it sees a 2xx, so a delivery can arrive more
+	// than once. The event id is recorded in the same statement that
+	// applies the event, so it's remembered exactly when it took effect
+	// and a redelivery changes nothing.
+	if params.Event == "user.downgraded" {
+		var n int64
+		n, err = cfg.db.CancelSubscription(r.Context(), database.CancelSubscriptionParams{
+			UserID:		params.Data.UserID,
+			EventID:	params.ID,
+			Event:		params.Event,
+		})
+		if err == nil && n == 0 {
+			err = sql.ErrNoRows
+		}
+	} else {
+		expiresAt := time.Now().AddDate(0, 1, 0)
+		if params.Data.ExpiresAt != nil {
+			expiresAt = *params.Data.ExpiresAt
+		}
+		_, err = cfg.db.RenewSubscription(r.Context(), database.RenewSubscriptionParams{
+			EventID:	params.ID,
+			Event:		params.Event,
+			UserID:		params.Data.UserID,
+			ExpiresAt:	expiresAt.UTC(),
+		})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		// Nothing was applied: the user is unknown, or this is a redelivery
+		_, err = cfg.db.GetUserByID(r.Context(), params.Data.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		} else if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update subscription", err)
+		return
+	}
+	cfg.metrics.WebhookEvents.WithLabelValues(params.Event).Inc()
+
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+
+func (cfg *apiConfig) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := cfg.db.GetSubscriptionsByUser(r.Context(), requestUserID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get subscription", err)
+		return
+	}
+
+
+	status := SubscriptionStatus{
+		Status:		"inactive",
+		History:	[]Subscription{},
+	}
+	for _, subscription := range subscriptions {
+		converted := Subscription{
+			ID:			subscription.ID,
+			StartedAt:	subscription.StartedAt,
+			ExpiresAt:	subscription.ExpiresAt,
+			EndReason:	subscription.EndReason.String,
+		}
+		if subscription.EndedAt.Valid {
+			converted.EndedAt = &subscription.EndedAt.Time
+		} else {
+			graceEndsAt := subscription.ExpiresAt.Add(cfg.config.Subscriptions.GracePeriod)
+			status.IsChirpyRed = true
+			status.Status = "active"
+			if time.Now().After(subscription.ExpiresAt) {
+				status.Status = "grace_period"
+			}
+			status.ExpiresAt = &converted.ExpiresAt
+			status.GracePeriodEndsAt = &graceEndsAt
+		}
+		status.History = append(status.History, converted)
+	}
+
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+
+// expireSubscriptions ends subscriptions that have lapsed past the grace
+// period every interval until ctx is done. Replicas may all run it; a
+// subscription is only ended once.
+func (cfg *apiConfig) expireSubscriptions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-cfg.config.Subscriptions.GracePeriod).UTC()
+		expired, err := cfg.db.ExpireSubscriptions(ctx, cutoff)
+		if err != nil {
+			slog.ErrorContext(ctx, "Couldn't expire subscriptions", slog.Any("error", err))
+		}
+		for _, subscription := range expired {
+			slog.InfoContext(ctx, "Subscription expired",
+				slog.String("user_id", subscription.UserID.String()),
+				slog.Time("expires_at", subscription.ExpiresAt))
+		}
+		cfg.metrics.SubscriptionsExpired.Add(float64(len(expired)))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
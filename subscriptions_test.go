@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/google/uuid"
+)
+
+// postWebhook delivers event to the Polka webhook signed with secret as
+// if it had been sent at sentAt.
+func postWebhook(t *testing.T, server *httptest.Server, secret string, sentAt time.Time, event any) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	req, err := http.NewRequest("POST", server.URL+"/api/polka/webhooks", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.WebhookTimestampHeader, strconv.FormatInt(sentAt.Unix(), 10))
+	req.Header.Set(auth.WebhookSignatureHeader, auth.SignWebhook(secret, sentAt, body))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("webhook delivery failed: %v", err)
+	}
+	res.Body.Close()
+	return res
+}
+
+// upgrade makes user Chirpy Red for a month through the webhook.
+func upgrade(t *testing.T, server *httptest.Server, user testUser) {
+	t.Helper()
+
+	event := map[string]any{"id": uuid.NewString(), "event": "user.upgraded", "data": map[string]any{"user_id": user.ID}}
+	if res := postWebhook(t, server, testPolkaSecret, time.Now(), event); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 upgrading %s, got %d", user.Email, res.StatusCode)
+	}
+}
+
+func TestPolkaWebhook(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	event := func(id, name string) map[string]any {
+		return map[string]any{"id": id, "event": name, "data": map[string]any{"user_id": user.ID}}
+	}
+	now := time.Now()
+
+	if res := doJSON(t, "POST", server.URL+"/api/polka/webhooks", "", event("evt_1", "user.upgraded"), nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a signature, got %d", res.StatusCode)
+	}
+	if res := postWebhook(t, server, "wrong", now, event("evt_1", "user.upgraded")); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with the wrong secret, got %d", res.StatusCode)
+	}
+	if res := postWebhook(t, server, testPolkaSecret, now.Add(-time.Hour), event("evt_1", "user.upgraded")); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a stale delivery, got %d", res.StatusCode)
+	}
+	if res := postWebhook(t, server, testPolkaSecret, now, map[string]any{"event": "user.upgraded"}); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 without an event id, got %d", res.StatusCode)
+	}
+	if relogin := signIn(t, server, "walt@breakingbad.com"); relogin.IsChirpyRed {
+		t.Fatal("a refused delivery upgraded the user")
+	}
+
+	if res := postWebhook(t, server, testPolkaSecret, now, event("evt_2", "user.payment_failed")); res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 for an ignored event, got %d", res.StatusCode)
+	}
+	if relogin := signIn(t, server, "walt@breakingbad.com"); relogin.IsChirpyRed {
+		t.Fatal("an ignored event upgraded the user")
+	}
+
+	if res := postWebhook(t, server, testPolkaSecret, now, event("evt_3", "user.upgraded")); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.StatusCode)
+	}
+	if relogin := signIn(t, server, "walt@breakingbad.com"); !relogin.IsChirpyRed {
+		t.Error("expected the user to be upgraded")
+	}
+	if res := postWebhook(t, server, testPolkaSecret, now, event("evt_3", "user.upgraded")); res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected a redelivery to be acknowledged, got %d", res.StatusCode)
+	}
+
+	unknown := map[string]any{"id": "evt_4", "event": "user.upgraded", "data": map[string]any{"user_id": uuid.New()}}
+	if res := postWebhook(t, server, testPolkaSecret, now, unknown); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", res.StatusCode)
+	}
+}
+
+func TestPolkaWebhookSecretRotation(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Polka.Secrets = []string{"new-secret", "old-secret"}
+	})
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+
+	for i, delivery := range []struct {
+		secret string
+		userID uuid.UUID
+	}{
+		{"old-secret", walt.ID},
+		{"new-secret", jesse.ID},
+	} {
+		event := map[string]any{"id": fmt.Sprintf("evt_%d", i), "event": "user.upgraded", "data": map[string]any{"user_id": delivery.userID}}
+		if res := postWebhook(t, server, delivery.secret, time.Now(), event); res.StatusCode != http.StatusNoContent {
+			t.Errorf("expected 204 signed with %s, got %d", delivery.secret, res.StatusCode)
+		}
+	}
+	if res := postWebhook(t, server, testPolkaSecret, time.Now(), map[string]any{"id": "evt_2", "event": "user.upgraded"}); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a secret that was rotated out, got %d", res.StatusCode)
+	}
+}
+
+func TestPolkaWebhookUnconfigured(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) { cfg.Polka.Secrets = nil })
+	user := signUp(t, server, "walt@breakingbad.com")
+
+	event := map[string]any{"id": "evt_1", "event": "user.upgraded", "data": map[string]any{"user_id": user.ID}}
+	if res := postWebhook(t, server, "", time.Now(), event); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with no secrets configured, got %d", res.StatusCode)
+	}
+}
+
+func getSubscription(t *testing.T, server *httptest.Server, token string) SubscriptionStatus {
+	t.Helper()
+
+	var status SubscriptionStatus
+	if res := doJSON(t, "GET", server.URL+"/api/users/me/subscription", token, nil, &status); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 getting the subscription, got %d", res.StatusCode)
+	}
+	return status
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	deliver := func(id, name string, data map[string]any) {
+		t.Helper()
+		data["user_id"] = walt.ID
+		event := map[string]any{"id": id, "event": name, "data": data}
+		if res := postWebhook(t, server, testPolkaSecret, time.Now(), event); res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204 for %s, got %d", name, res.StatusCode)
+		}
+	}
+
+	if res := doJSON(t, "GET", server.URL+"/api/users/me/subscription", "", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+	if status := getSubscription(t, server, walt.Token); status.Status != "inactive" || status.IsChirpyRed || len(status.History) != 0 {
+		t.Errorf("expected no subscription, got %+v", status)
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, 30).UTC().Truncate(time.Second)
+	deliver("evt_1", "user.upgraded", map[string]any{"expires_at": expiresAt})
+	status := getSubscription(t, server, walt.Token)
+	if status.Status != "active" || !status.IsChirpyRed || status.ExpiresAt == nil || !status.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected an active subscription, got %+v", status)
+	}
+	if status.GracePeriodEndsAt == nil || !status.GracePeriodEndsAt.Equal(expiresAt.Add(config.Default().Subscriptions.GracePeriod)) {
+		t.Errorf("unexpected grace period end %v", status.GracePeriodEndsAt)
+	}
+
+	renewedUntil := expiresAt.AddDate(0, 1, 0)
+	deliver("evt_2", "user.renewed", map[string]any{"expires_at": renewedUntil})
+	status = getSubscription(t, server, walt.Token)
+	if len(status.History) != 1 || status.ExpiresAt == nil || !status.ExpiresAt.Equal(renewedUntil) {
+		t.Errorf("expected the renewal to extend the subscription, got %+v", status)
+	}
+
+	deliver("evt_3", "user.downgraded", map[string]any{})
+	status = getSubscription(t, server, walt.Token)
+	if status.Status != "inactive" || status.IsChirpyRed || status.ExpiresAt != nil {
+		t.Errorf("expected the downgrade to end the subscription, got %+v", status)
+	}
+	if len(status.History) != 1 || status.History[0].EndedAt == nil || status.History[0].EndReason != "downgraded" {
+		t.Errorf("expected a downgraded subscription in the history, got %+v", status.History)
+	}
+	if relogin := signIn(t, server, "walt@breakingbad.com"); relogin.IsChirpyRed {
+		t.Error("expected the user to lose Chirpy Red")
+	}
+
+	// Without an expiry from Polka a subscription runs for a month
+	deliver("evt_4", "user.upgraded", map[string]any{})
+	status = getSubscription(t, server, walt.Token)
+	if len(status.History) != 2 || status.Status != "active" || status.ExpiresAt == nil || status.ExpiresAt.Before(time.Now().AddDate(0, 0, 27)) {
+		t.Errorf("expected a new month-long subscription, got %+v", status)
+	}
+}
+
+func TestSubscriptionExpiry(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Subscriptions.GracePeriod = time.Hour
+		cfg.Subscriptions.CheckInterval = 10 * time.Millisecond
+	})
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+	// walt lapsed past the grace period; jesse is still in it
+	for i, lapsed := range []struct {
+		user testUser
+		ago  time.Duration
+	}{
+		{walt, 2 * time.Hour},
+		{jesse, 30 * time.Minute},
+	} {
+		data := map[string]any{"user_id": lapsed.user.ID, "expires_at": time.Now().Add(-lapsed.ago)}
+		event := map[string]any{"id": fmt.Sprintf("evt_%d", i), "event": "user.upgraded", "data": data}
+		if res := postWebhook(t, server, testPolkaSecret, time.Now(), event); res.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", res.StatusCode)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for signIn(t, server, "walt@breakingbad.com").IsChirpyRed {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the lapsed subscription to be expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := getSubscription(t, server, walt.Token); status.Status != "inactive" || len(status.History) != 1 || status.History[0].EndReason != "expired" {
+		t.Errorf("expected an expired subscription, got %+v", status)
+	}
+
+	if status := getSubscription(t, server, jesse.Token); status.Status != "grace_period" || !status.IsChirpyRed {
+		t.Errorf("expected a subscription in its grace period, got %+v", status)
+	}
+	if !signIn(t, server, "jesse@breakingbad.com").IsChirpyRed {
+		t.Error("expected the grace period to keep Chirpy Red")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ThreadNode is one chirp in a conversation tree. Deleted chirps are kept
+// as tombstones, with their body and author blanked, so the replies under
+// them stay in place.
+type ThreadNode struct {
+	Chirp
+	Deleted bool          `json:"deleted"`
+	Depth   int           `json:"depth"`
+	Replies []*ThreadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) handleGetThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't parse chirpID", err)
+		return
+	}
+
+
+	viewer := viewerID(r)
+
+	// Deleted chirps still know their thread, so asking for the thread of a
+	// tombstone works too.
+	threadID, err := cfg.db.GetChirpThreadID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
+		return
+	}
+
+
+	rows, err := cfg.db.GetThread(r.Context(), threadID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve thread", err)
+		return
+	}
+
+
+	chirps := chirpsFromDB(rows)
+	if err := cfg.markLikedByMe(r.Context(), chirps, viewer); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, buildThread(threadID, rows, chirps))
+}
+
+
+// buildThread assembles the rows of a thread into a tree rooted at
+// threadID. rows must be ordered oldest first, which keeps replies in
+// conversation order. A reply whose parent is gone (its author's account
+// was deleted) is hung off the root rather than dropped.
+func buildThread(threadID uuid.UUID, rows []database.Chirp, chirps []Chirp) *ThreadNode {
+	nodes := make(map[uuid.UUID]*ThreadNode, len(rows))
+	for i, row := range rows {
+		node := &ThreadNode{Chirp: chirps[i], Replies: []*ThreadNode{}}
+		if row.DeletedAt.Valid {
+			node.Chirp = Chirp{
+				ID:          row.ID,
+				CreatedAt:   row.CreatedAt,
+				UpdatedAt:   row.UpdatedAt,
+				InReplyToID: row.InReplyToID,
+				ThreadID:    row.ThreadID,
+			}
+			node.Deleted = true
+		}
+		nodes[row.ID] = node
+	}
+
+	root, ok := nodes[threadID]
+	if !ok {
+		root = &ThreadNode{
+			Chirp:   Chirp{ID: threadID, ThreadID: threadID},
+			Deleted: true,
+			Replies: []*ThreadNode{},
+		}
+	}
+
+	for _, row := range rows {
+		if row.ID == threadID {
+			continue
+		}
+		parent, ok := nodes[row.InReplyToID.UUID]
+		if !row.InReplyToID.Valid || !ok {
+			parent = root
+		}
+		parent.Replies = append(parent.Replies, nodes[row.ID])
+	}
+
+	setDepth(root, 0)
+	return root
+}
+
+func setDepth(node *ThreadNode, depth int) {
+	node.Depth = depth
+	for _, reply := range node.Replies {
+		setDepth(reply, depth+1)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// reply posts body as user in reply to parent.
+func reply(t *testing.T, server *httptest.Server, user testUser, parent uuid.UUID, body string) Chirp {
+	t.Helper()
+
+	var chirp Chirp
+	params := map[string]any{"body": body, "in_reply_to_id": parent}
+	if res := doJSON(t, "POST", server.URL+"/api/chirps", user.Token, params, &chirp); res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 replying, got %d", res.StatusCode)
+	}
+	return chirp
+}
+
+func TestThread(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+
+	root := postChirp(t, server, walt, "We need to cook")
+	answer := reply(t, server, jesse, root.ID, "Yeah, science")
+	followUp := reply(t, server, walt, answer.ID, "Tread lightly")
+	second := reply(t, server, jesse, root.ID, "Where though")
+
+	// The thread is the same from any chirp in it
+	for _, id := range []uuid.UUID{root.ID, followUp.ID} {
+		var thread ThreadNode
+		if res := doJSON(t, "GET", server.URL+"/api/chirps/"+id.String()+"/thread", "", nil, &thread); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		if thread.ID != root.ID || thread.Depth != 0 || thread.Deleted || len(thread.Replies) != 2 {
+			t.Fatalf("unexpected root %+v", thread)
+		}
+		if thread.Replies[0].ID != answer.ID || thread.Replies[1].ID != second.ID {
+			t.Errorf("expected the replies in conversation order, got %v, %v", thread.Replies[0].ID, thread.Replies[1].ID)
+		}
+		if nested := thread.Replies[0].Replies; len(nested) != 1 || nested[0].ID != followUp.ID || nested[0].Depth != 2 {
+			t.Errorf("expected the follow-up under the answer, got %+v", nested)
+		}
+	}
+
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/"+uuid.NewString()+"/thread", "", nil, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown chirp, got %d", res.StatusCode)
+	}
+}
+
+func TestThreadTombstone(t *testing.T) {
+	server := newTestServer(t)
+	walt := signUp(t, server, "walt@breakingbad.com")
+	jesse := signUp(t, server, "jesse@breakingbad.com")
+
+	root := postChirp(t, server, walt, "I am the danger")
+	answer := reply(t, server, jesse, root.ID, "Yo, Mr. White")
+
+	url := server.URL + "/api/chirps/" + root.ID.String()
+	if res := doJSON(t, "POST", url+"/likes", jesse.Token, nil, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 liking, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "DELETE", url, walt.Token, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 deleting, got %d", res.StatusCode)
+	}
+
+	// Asking through the reply finds the thread, and the tombstone keeps
+	// its place without the body, author or likes
+	var thread ThreadNode
+	if res := doJSON(t, "GET", server.URL+"/api/chirps/"+answer.ID.String()+"/thread", jesse.Token, nil, &thread); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if thread.ID != root.ID || !thread.Deleted {
+		t.Fatalf("expected the root to be a tombstone, got %+v", thread)
+	}
+	if thread.Body != "" || thread.UserID != uuid.Nil || thread.LikeCount != 0 || thread.LikedByMe != nil {
+		t.Errorf("expected the tombstone to be blank, got %+v", thread.Chirp)
+	}
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != answer.ID || thread.Replies[0].Body != "Yo, Mr. White" {
+		t.Errorf("expected the reply to stay under the tombstone, got %+v", thread.Replies)
+	}
+	if liked := thread.Replies[0].LikedByMe; liked == nil || *liked {
+		t.Errorf("expected the viewer's likes on live replies, got %v", liked)
+	}
+}
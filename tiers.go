@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/NachoGz/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// tierPolicy looks up what the user's tier allows them to do. Perks are
+// granted by the policy table in the config, so handlers ask the policy
+// rather than checking is_chirpy_red themselves.
+func (cfg *apiConfig) tierPolicy(ctx context.Context, userID uuid.UUID) (config.TierPolicy, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return config.TierPolicy{}, err
+	}
+	return cfg.config.Tiers.For(user.IsChirpyRed), nil
+}
+
+// dailyChirpsUsed counts what the user has spent of the daily quota a
+// chirp going out at the given time would draw on. Every chirp counts once,
+// at the time it goes out: a scheduled chirp is pending until then and a
+// posted chirp afterwards. Counting everything within a day either side of
+// at keeps every 24-hour window containing it under the cap, so a chirp
+// scheduled for next week doesn't spend today's allowance.
+func (cfg *apiConfig) dailyChirpsUsed(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	posted, err := cfg.db.CountChirpsSince(ctx, database.CountChirpsSinceParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Since:  at.Add(-24 * time.Hour).UTC(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	pending, err := cfg.db.CountScheduledChirpsBetween(ctx, database.CountScheduledChirpsBetweenParams{
+		UserID: userID,
+		After:  at.Add(-24 * time.Hour).UTC(),
+		Before: at.Add(24 * time.Hour).UTC(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return posted + pending, nil
+}
+
+// userLocks serializes work per user, such as checking a quota and then
+// spending it, so concurrent requests can't both pass the check. It only
+// covers this process.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[uuid.UUID]*userLock
+}
+
+type userLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// lock blocks until no one else holds userID's lock and returns the
+// function that releases it.
+func (l *userLocks) lock(userID uuid.UUID) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[uuid.UUID]*userLock{}
+	}
+	lock, ok := l.locks[userID]
+	if !ok {
+		lock = &userLock{}
+		l.locks[userID] = lock
+	}
+	lock.waiters++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(l.locks, userID)
+		}
+	}
+}
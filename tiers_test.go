@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUserLocks(t *testing.T) {
+	var locks userLocks
+	walt, jesse := uuid.New(), uuid.New()
+
+	unlock := locks.lock(walt)
+
+	// Someone else's lock is independent
+	locks.lock(jesse)()
+
+	acquired := make(chan func())
+	go func() { acquired <- locks.lock(walt) }()
+	select {
+	case <-acquired:
+		t.Fatal("expected the second lock to wait for the first")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case unlock = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected the second lock once the first was released")
+	}
+	unlock()
+
+	if len(locks.locks) != 0 {
+		t.Errorf("expected released locks to be forgotten, got %d", len(locks.locks))
+	}
+}
@@ -2,49 +2,89 @@ package main
 
 import (
 	"github.com/NachoGz/chirpy/internal/auth"
+	"github.com/NachoGz/chirpy/internal/database"
+	"database/sql"
+	"errors"
 	"time"
 	"net/http"
-	"log"
+	"log/slog"
 )
 
+// handleRefreshToken swaps a refresh token for a new access token and a new
+// refresh token. The presented token is revoked; presenting it again later
+// is treated as theft and revokes every token in its family.
 func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't get the bearer token", err)
 		return
 	}
-	log.Println("Bearer token:", token)
 
 
-	ref_token, err := cfg.db.GetRefreshToken(r.Context(), token)
-	log.Printf("Looking for refresh token: %v", ref_token.Token)
-
+	new_refresh_token, err := auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "The token doesn't exist", err)
+		respondWithError(w, http.StatusInternalServerError, "Error generating refresh token", err)
 		return
-	} else if time.Now().After(ref_token.ExpiresAt) {
-		respondWithError(w, http.StatusUnauthorized, "Refresh token has expired", nil)
+	}
+
+
+	ref_token, err := cfg.db.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		OldTokenHash:	auth.HashRefreshToken(token),
+		NewTokenHash:	auth.HashRefreshToken(new_refresh_token),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.rejectRefreshToken(w, r, token)
 		return
-	}  else if ref_token.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "Refresh token is revoked", nil)
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error rotating refresh token", err)
 		return
 	}
 
 	// Generate JWT with expiration time
-	access_token, err := auth.MakeJWT(ref_token.UserID.UUID, cfg.secret, time.Duration(3600)*time.Second)
+	access_token, err := cfg.jwtKeys.MakeJWT(ref_token.UserID.UUID, time.Duration(3600)*time.Second)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error generating JWT", err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, struct {
-		Token	string `json:"token"`
+		Token			string `json:"token"`
+		RefreshToken	string `json:"refresh_token"`
 	}{
-		Token:	access_token,
+		Token:			access_token,
+		RefreshToken:	new_refresh_token,
 	})
 }
 
 
+// rejectRefreshToken works out why a refresh token couldn't be rotated and
+// responds accordingly, revoking the token's family if it was reused.
+func (cfg *apiConfig) rejectRefreshToken(w http.ResponseWriter, r *http.Request, token string) {
+	ref_token, err := cfg.db.GetRefreshToken(r.Context(), auth.HashRefreshToken(token))
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "The token doesn't exist", err)
+		return
+	}
+
+	if ref_token.ReplacedByHash.Valid {
+		slog.WarnContext(r.Context(), "Suspected refresh token theft: rotated token reused, revoking family",
+			slog.String("user_id", ref_token.UserID.UUID.String()),
+			slog.String("family_id", ref_token.FamilyID.String()))
+		if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), ref_token.FamilyID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't revoke the token family", err)
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, "Refresh token has already been used", nil)
+		return
+	} else if ref_token.RevokedAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Refresh token is revoked", nil)
+		return
+	}
+
+	respondWithError(w, http.StatusUnauthorized, "Refresh token has expired", nil)
+}
+
+
 func (cfg *apiConfig) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -53,7 +93,7 @@ func (cfg *apiConfig) handleRevokeToken(w http.ResponseWriter, r *http.Request)
 	}
 
 
-	if err := cfg.db.RevokeRefreshToken(r.Context(), token); err != nil {
+	if err := cfg.db.RevokeRefreshToken(r.Context(), auth.HashRefreshToken(token)); err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't revoke the refresh token", err)
 		return
 	}
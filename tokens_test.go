@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func TestRefreshToken(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	url := server.URL + "/api/refresh"
+
+	var rotated tokenPair
+	if res := doJSON(t, "POST", url, user.RefreshToken, nil, &rotated); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if rotated.Token == "" || rotated.RefreshToken == "" || rotated.RefreshToken == user.RefreshToken {
+		t.Fatalf("expected a new token pair, got %+v", rotated)
+	}
+
+	// The new access token works
+	if res := doJSON(t, "POST", server.URL+"/api/chirps", rotated.Token, map[string]string{"body": "hi"}, nil); res.StatusCode != http.StatusCreated {
+		t.Errorf("expected the refreshed access token to work, got %d", res.StatusCode)
+	}
+
+	// Replaying the old refresh token is treated as theft and kills the family
+	if res := doJSON(t, "POST", url, user.RefreshToken, nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 reusing a rotated token, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", url, rotated.RefreshToken, nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for the rest of the family, got %d", res.StatusCode)
+	}
+
+	if res := doJSON(t, "POST", url, "not-a-token", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown token, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", url, "", nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+
+	if res := doJSON(t, "POST", server.URL+"/api/revoke", user.RefreshToken, nil, nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.StatusCode)
+	}
+	if res := doJSON(t, "POST", server.URL+"/api/refresh", user.RefreshToken, nil, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 refreshing a revoked token, got %d", res.StatusCode)
+	}
+}
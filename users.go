@@ -66,19 +66,21 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
+		cfg.metrics.FailedLogins.Inc()
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
 	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
 	if err != nil {
+		cfg.metrics.FailedLogins.Inc()
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
 
 	// Generate JWT with expiration time
-	access_token, err := auth.MakeJWT(user.ID, cfg.secret, time.Duration(3600)*time.Second)
+	access_token, err := cfg.jwtKeys.MakeJWT(user.ID, time.Duration(3600)*time.Second)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error generating JWT", err)
 		return
@@ -92,13 +94,15 @@ func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token: refresh_token,
+		TokenHash: auth.HashRefreshToken(refresh_token),
 		UserID: uuid.NullUUID{UUID:	user.ID, Valid: true},
+		FamilyID: uuid.New(),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating refresh token", err)
 		return
 	}
+	cfg.metrics.Logins.Inc()
 
 
 	response := struct {
@@ -138,20 +142,7 @@ func (cfg *apiConfig) handleUpdateUserInfo(w http.ResponseWriter, r *http.Reques
 	}
 
 
-	// Extract token from the header
-	bearer_token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Missing or invalid authorization token", err)
-		return
-	}
-
-
-	// Validate the JWT and extract user ID
-	userID, err := auth.ValidateJWT(bearer_token, cfg.secret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect token", err)
-		return
-	}
+	userID := requestUserID(r)
 
 
 	hashed_passwd, err := auth.HashPassword(params.Password)
@@ -180,47 +171,3 @@ func (cfg *apiConfig) handleUpdateUserInfo(w http.ResponseWriter, r *http.Reques
 		IsChirpyRed:	user.IsChirpyRed,
 	})
 }
-
-
-func (cfg *apiConfig) handleUpgradedToChirpyRed(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Event string `json:"event"`
-		Data struct {
-			UserID uuid.UUID `json:"user_id"`
-		} `json:"data"`
-	}
-
-	api_key, err := auth.GetAPIKey(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "ApiKey not found", err)
-		return
-	}
-	if cfg.PolkaKey != api_key {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect ApiKey", err)
-		return
-	}
-
-
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
-		return
-	}
-
-	if params.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
-
-
-	err = cfg.db.UpgradeToChirpyRed(r.Context(), params.Data.UserID)
-	if err != nil {
-		respondWithError(w, http.StatusNotFound, "User not found", err)
-		return
-	}
-
-
-	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
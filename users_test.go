@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NachoGz/chirpy/internal/config"
+	"github.com/google/uuid"
+)
+
+func TestCreateUser(t *testing.T) {
+	server := newTestServer(t)
+
+	var user User
+	res := doJSON(t, "POST", server.URL+"/api/users", "", map[string]string{
+		"email":    "walt@breakingbad.com",
+		"password": "04234",
+	}, &user)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", res.StatusCode)
+	}
+	if user.ID == uuid.Nil || user.Email != "walt@breakingbad.com" || user.IsChirpyRed {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+
+	if user.Token == "" || user.RefreshToken == "" {
+		t.Errorf("expected both tokens, got %+v", user)
+	}
+
+	cases := map[string]map[string]string{
+		"wrong password": {"email": "walt@breakingbad.com", "password": "wrong"},
+		"unknown email":  {"email": "jesse@breakingbad.com", "password": testPassword},
+	}
+	for name, credentials := range cases {
+		res := doJSON(t, "POST", server.URL+"/api/login", "", credentials, nil)
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, res.StatusCode)
+		}
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	server := newTestServer(t)
+	user := signUp(t, server, "walt@breakingbad.com")
+	update := map[string]string{"email": "heisenberg@breakingbad.com", "password": "say-my-name"}
+
+	if res := doJSON(t, "PUT", server.URL+"/api/users", "", update, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+	}
+
+	var updated User
+	if res := doJSON(t, "PUT", server.URL+"/api/users", user.Token, update, &updated); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if updated.ID != user.ID || updated.Email != "heisenberg@breakingbad.com" {
+		t.Errorf("unexpected user %+v", updated)
+	}
+
+	if res := doJSON(t, "POST", server.URL+"/api/login", "", update, nil); res.StatusCode != http.StatusOK {
+		t.Errorf("expected to log in with the new credentials, got %d", res.StatusCode)
+	}
+}
+
+func TestAdminResetIsDevOnly(t *testing.T) {
+	prod := newTestServer(t, func(cfg *config.Config) { cfg.Platform = "prod" })
+	signUp(t, prod, "walt@breakingbad.com")
+	if res := doJSON(t, "POST", prod.URL+"/admin/reset", "", nil, nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 outside dev, got %d", res.StatusCode)
+	}
+	signIn(t, prod, "walt@breakingbad.com")
+
+	dev := newTestServer(t)
+	signUp(t, dev, "walt@breakingbad.com")
+	if res := doJSON(t, "POST", dev.URL+"/admin/reset", "", nil, nil); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 in dev, got %d", res.StatusCode)
+	}
+	credentials := map[string]string{"email": "walt@breakingbad.com", "password": testPassword}
+	if res := doJSON(t, "POST", dev.URL+"/api/login", "", credentials, nil); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected users to be gone after a reset, got %d", res.StatusCode)
+	}
+}